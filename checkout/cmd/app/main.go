@@ -6,6 +6,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"route256/checkout/internal/api/checkout_v1"
 	"route256/checkout/internal/clients/lomsclient"
 	"route256/checkout/internal/clients/productsclient"
@@ -14,10 +15,12 @@ import (
 	"route256/checkout/internal/service"
 	desc "route256/checkout/pkg/checkout_v1"
 	"route256/libs/interceptors"
+	"route256/libs/limiter"
 	log "route256/libs/logger"
 	"route256/libs/metrics"
 	"route256/libs/tracing"
 	"sync"
+	"syscall"
 
 	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/grpc-ecosystem/grpc-opentracing/go/otgrpc"
@@ -40,7 +43,10 @@ func main() {
 	log.Init(*develMode, zap.String("service", "checkout"))
 	err := config.Init()
 	if err != nil {
-		log.Fatal("config init", zap.Error(err))
+		log.Fatal(context.Background(), "config init", zap.Error(err))
+	}
+	if err := log.InitLevel(*develMode, config.ConfigData.LogLevel); err != nil {
+		log.Fatal(context.Background(), "log level init", zap.Error(err))
 	}
 	tracing.Init("checkout")
 
@@ -48,16 +54,16 @@ func main() {
 	defer lomsClient.Close()
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	productsClient := productsclient.New(ctx, config.ConfigData.Services.ProductService)
-	defer productsClient.Close()
 	pool, err := pgxpool.Connect(ctx, os.Getenv("DATABASE_URL"))
 	if err != nil {
-		log.Fatal("failed to connect db", zap.Error(err))
+		log.Fatal(ctx, "failed to connect db", zap.Error(err))
 	}
 	defer pool.Close()
 	if err := pool.Ping(ctx); err != nil {
-		log.Fatal("failed to ping db", zap.Error(err))
+		log.Fatal(ctx, "failed to ping db", zap.Error(err))
 	}
+	productsClient := productsclient.New(ctx, config.ConfigData.Services.ProductService, limiter.NewPostgresStore(pool))
+	defer productsClient.Close()
 
 	metricsServerDone := &sync.WaitGroup{}
 	metricsServerDone.Add(1)
@@ -68,8 +74,10 @@ func main() {
 	go func(ctx context.Context) {
 		defer metricsServerDone.Done()
 		http.Handle("/metrics", metrics.New())
+		// PUT принимается только с токеном администратора или, если он не задан, только с localhost
+		http.Handle("/log/level", interceptors.NewAdminHTTPHandler(config.ConfigData.AdminToken, log.LevelHandler())) // GET - текущий уровень логов, PUT {"level":"debug"} - изменить
 
-		log.Info("listening http for metrics", zap.String("addr", *metricsPort))
+		log.Info(ctx, "listening http for metrics", zap.String("addr", *metricsPort))
 		if err := metricsServer.ListenAndServe(); err != nil {
 			log.Error(ctx, "Error starting metrics handler", zap.Error(err))
 		}
@@ -82,13 +90,18 @@ func main() {
 
 	lis, err := net.Listen("tcp", *grpcPort)
 	if err != nil {
-		log.Fatal("failed to listen", zap.Error(err))
+		log.Fatal(ctx, "failed to listen", zap.Error(err))
 	}
 
 	s := grpc.NewServer(
 		grpc.UnaryInterceptor(
 			grpcMiddleware.ChainUnaryServer(
 				otgrpc.OpenTracingServerInterceptor(opentracing.GlobalTracer()),
+				interceptors.NewAdminInterceptor( // сброс кэша товаров на всех репликах доступен только администратору
+					config.ConfigData.AdminToken,
+					"/route256.checkout_v1.CheckoutService/InvalidateProductsCache",
+				),
+				interceptors.NewRateLimitInterceptor(ctx, config.ConfigData.RateLimit),
 				interceptors.LoggingInterceptor,
 			),
 		),
@@ -97,10 +110,18 @@ func main() {
 	reflection.Register(s)
 	desc.RegisterCheckoutServiceServer(s, checkout_v1.NewCheckoutV1(checkoutService))
 
-	log.Info("server listening", zap.String("grpcAddr", *grpcPort))
+	log.Info(ctx, "server listening", zap.String("grpcAddr", *grpcPort))
+
+	go func() { // при остановке сервиса завершаем обработку запросов, чтобы отработали defer, в т.ч. сохранение кэша товаров
+		sigterm := make(chan os.Signal, 1)
+		signal.Notify(sigterm, syscall.SIGINT, syscall.SIGTERM)
+		<-sigterm
+		log.Info(ctx, "terminating: via signal")
+		s.GracefulStop()
+	}()
 
 	if err = s.Serve(lis); err != nil {
-		log.Fatal("failed to serve", zap.Error(err))
+		log.Fatal(ctx, "failed to serve", zap.Error(err))
 	}
 
 	if err := metricsServer.Shutdown(ctx); err != nil {
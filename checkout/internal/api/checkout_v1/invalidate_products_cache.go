@@ -0,0 +1,33 @@
+package checkout_v1
+
+import (
+	"context"
+	"route256/checkout/internal/service"
+	"route256/checkout/pkg/checkout_v1"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (i *Implementation) InvalidateProductsCache(ctx context.Context, req *checkout_v1.InvalidateProductsCacheRequest) (*checkout_v1.InvalidateProductsCacheResponse, error) {
+	skus := req.GetSkus()
+	all := req.GetAll()
+
+	span := opentracing.SpanFromContext(ctx)
+	if span != nil {
+		span.SetTag("SKUs", skus)
+		span.SetTag("all", all)
+	}
+
+	err := i.checkoutService.InvalidateProductsCache(ctx, skus, all)
+	if errors.Is(err, service.ErrNothingToInvalidate) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &checkout_v1.InvalidateProductsCacheResponse{}, nil
+}
@@ -35,7 +35,7 @@ func New(url string) Client {
 		grpc.WithUnaryInterceptor(otgrpc.OpenTracingClientInterceptor(opentracing.GlobalTracer())),
 	)
 	if err != nil {
-		log.Fatal("failed to connect to loms server", zap.Error(err))
+		log.Fatal(context.Background(), "failed to connect to loms server", zap.Error(err))
 	}
 
 	return &client{
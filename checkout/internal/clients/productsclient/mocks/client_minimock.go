@@ -18,6 +18,12 @@ import (
 type ClientMock struct {
 	t minimock.Tester
 
+	funcClearProducts          func(ctx context.Context) (err error)
+	inspectFuncClearProducts   func(ctx context.Context)
+	afterClearProductsCounter  uint64
+	beforeClearProductsCounter uint64
+	ClearProductsMock          mClientMockClearProducts
+
 	funcClose          func() (err error)
 	inspectFuncClose   func()
 	afterCloseCounter  uint64
@@ -35,6 +41,12 @@ type ClientMock struct {
 	afterGetProductsInfoCounter  uint64
 	beforeGetProductsInfoCounter uint64
 	GetProductsInfoMock          mClientMockGetProductsInfo
+
+	funcInvalidateProducts          func(ctx context.Context, skus []uint32) (err error)
+	inspectFuncInvalidateProducts   func(ctx context.Context, skus []uint32)
+	afterInvalidateProductsCounter  uint64
+	beforeInvalidateProductsCounter uint64
+	InvalidateProductsMock          mClientMockInvalidateProducts
 }
 
 // NewClientMock returns a mock for productsclient.Client
@@ -44,6 +56,9 @@ func NewClientMock(t minimock.Tester) *ClientMock {
 		controller.RegisterMocker(m)
 	}
 
+	m.ClearProductsMock = mClientMockClearProducts{mock: m}
+	m.ClearProductsMock.callArgs = []*ClientMockClearProductsParams{}
+
 	m.CloseMock = mClientMockClose{mock: m}
 
 	m.GetProductMock = mClientMockGetProduct{mock: m}
@@ -52,9 +67,227 @@ func NewClientMock(t minimock.Tester) *ClientMock {
 	m.GetProductsInfoMock = mClientMockGetProductsInfo{mock: m}
 	m.GetProductsInfoMock.callArgs = []*ClientMockGetProductsInfoParams{}
 
+	m.InvalidateProductsMock = mClientMockInvalidateProducts{mock: m}
+	m.InvalidateProductsMock.callArgs = []*ClientMockInvalidateProductsParams{}
+
 	return m
 }
 
+type mClientMockClearProducts struct {
+	mock               *ClientMock
+	defaultExpectation *ClientMockClearProductsExpectation
+	expectations       []*ClientMockClearProductsExpectation
+
+	callArgs []*ClientMockClearProductsParams
+	mutex    sync.RWMutex
+}
+
+// ClientMockClearProductsExpectation specifies expectation struct of the Client.ClearProducts
+type ClientMockClearProductsExpectation struct {
+	mock    *ClientMock
+	params  *ClientMockClearProductsParams
+	results *ClientMockClearProductsResults
+	Counter uint64
+}
+
+// ClientMockClearProductsParams contains parameters of the Client.ClearProducts
+type ClientMockClearProductsParams struct {
+	ctx context.Context
+}
+
+// ClientMockClearProductsResults contains results of the Client.ClearProducts
+type ClientMockClearProductsResults struct {
+	err error
+}
+
+// Expect sets up expected params for Client.ClearProducts
+func (mmClearProducts *mClientMockClearProducts) Expect(ctx context.Context) *mClientMockClearProducts {
+	if mmClearProducts.mock.funcClearProducts != nil {
+		mmClearProducts.mock.t.Fatalf("ClientMock.ClearProducts mock is already set by Set")
+	}
+
+	if mmClearProducts.defaultExpectation == nil {
+		mmClearProducts.defaultExpectation = &ClientMockClearProductsExpectation{}
+	}
+
+	mmClearProducts.defaultExpectation.params = &ClientMockClearProductsParams{ctx}
+	for _, e := range mmClearProducts.expectations {
+		if minimock.Equal(e.params, mmClearProducts.defaultExpectation.params) {
+			mmClearProducts.mock.t.Fatalf("Expectation set by When has same params: %#v", *mmClearProducts.defaultExpectation.params)
+		}
+	}
+
+	return mmClearProducts
+}
+
+// Inspect accepts an inspector function that has same arguments as the Client.ClearProducts
+func (mmClearProducts *mClientMockClearProducts) Inspect(f func(ctx context.Context)) *mClientMockClearProducts {
+	if mmClearProducts.mock.inspectFuncClearProducts != nil {
+		mmClearProducts.mock.t.Fatalf("Inspect function is already set for ClientMock.ClearProducts")
+	}
+
+	mmClearProducts.mock.inspectFuncClearProducts = f
+
+	return mmClearProducts
+}
+
+// Return sets up results that will be returned by Client.ClearProducts
+func (mmClearProducts *mClientMockClearProducts) Return(err error) *ClientMock {
+	if mmClearProducts.mock.funcClearProducts != nil {
+		mmClearProducts.mock.t.Fatalf("ClientMock.ClearProducts mock is already set by Set")
+	}
+
+	if mmClearProducts.defaultExpectation == nil {
+		mmClearProducts.defaultExpectation = &ClientMockClearProductsExpectation{mock: mmClearProducts.mock}
+	}
+	mmClearProducts.defaultExpectation.results = &ClientMockClearProductsResults{err}
+	return mmClearProducts.mock
+}
+
+// Set uses given function f to mock the Client.ClearProducts method
+func (mmClearProducts *mClientMockClearProducts) Set(f func(ctx context.Context) (err error)) *ClientMock {
+	if mmClearProducts.defaultExpectation != nil {
+		mmClearProducts.mock.t.Fatalf("Default expectation is already set for the Client.ClearProducts method")
+	}
+
+	if len(mmClearProducts.expectations) > 0 {
+		mmClearProducts.mock.t.Fatalf("Some expectations are already set for the Client.ClearProducts method")
+	}
+
+	mmClearProducts.mock.funcClearProducts = f
+	return mmClearProducts.mock
+}
+
+// When sets expectation for the Client.ClearProducts which will trigger the result defined by the following
+// Then helper
+func (mmClearProducts *mClientMockClearProducts) When(ctx context.Context) *ClientMockClearProductsExpectation {
+	if mmClearProducts.mock.funcClearProducts != nil {
+		mmClearProducts.mock.t.Fatalf("ClientMock.ClearProducts mock is already set by Set")
+	}
+
+	expectation := &ClientMockClearProductsExpectation{
+		mock:   mmClearProducts.mock,
+		params: &ClientMockClearProductsParams{ctx},
+	}
+	mmClearProducts.expectations = append(mmClearProducts.expectations, expectation)
+	return expectation
+}
+
+// Then sets up Client.ClearProducts return parameters for the expectation previously defined by the When method
+func (e *ClientMockClearProductsExpectation) Then(err error) *ClientMock {
+	e.results = &ClientMockClearProductsResults{err}
+	return e.mock
+}
+
+// ClearProducts implements productsclient.Client
+func (mmClearProducts *ClientMock) ClearProducts(ctx context.Context) (err error) {
+	mm_atomic.AddUint64(&mmClearProducts.beforeClearProductsCounter, 1)
+	defer mm_atomic.AddUint64(&mmClearProducts.afterClearProductsCounter, 1)
+
+	if mmClearProducts.inspectFuncClearProducts != nil {
+		mmClearProducts.inspectFuncClearProducts(ctx)
+	}
+
+	mm_params := &ClientMockClearProductsParams{ctx}
+
+	// Record call args
+	mmClearProducts.ClearProductsMock.mutex.Lock()
+	mmClearProducts.ClearProductsMock.callArgs = append(mmClearProducts.ClearProductsMock.callArgs, mm_params)
+	mmClearProducts.ClearProductsMock.mutex.Unlock()
+
+	for _, e := range mmClearProducts.ClearProductsMock.expectations {
+		if minimock.Equal(e.params, mm_params) {
+			mm_atomic.AddUint64(&e.Counter, 1)
+			return e.results.err
+		}
+	}
+
+	if mmClearProducts.ClearProductsMock.defaultExpectation != nil {
+		mm_atomic.AddUint64(&mmClearProducts.ClearProductsMock.defaultExpectation.Counter, 1)
+		mm_want := mmClearProducts.ClearProductsMock.defaultExpectation.params
+		mm_got := ClientMockClearProductsParams{ctx}
+		if mm_want != nil && !minimock.Equal(*mm_want, mm_got) {
+			mmClearProducts.t.Errorf("ClientMock.ClearProducts got unexpected parameters, want: %#v, got: %#v%s\n", *mm_want, mm_got, minimock.Diff(*mm_want, mm_got))
+		}
+
+		mm_results := mmClearProducts.ClearProductsMock.defaultExpectation.results
+		if mm_results == nil {
+			mmClearProducts.t.Fatal("No results are set for the ClientMock.ClearProducts")
+		}
+		return (*mm_results).err
+	}
+	if mmClearProducts.funcClearProducts != nil {
+		return mmClearProducts.funcClearProducts(ctx)
+	}
+	mmClearProducts.t.Fatalf("Unexpected call to ClientMock.ClearProducts. %v", ctx)
+	return
+}
+
+// ClearProductsAfterCounter returns a count of finished ClientMock.ClearProducts invocations
+func (mmClearProducts *ClientMock) ClearProductsAfterCounter() uint64 {
+	return mm_atomic.LoadUint64(&mmClearProducts.afterClearProductsCounter)
+}
+
+// ClearProductsBeforeCounter returns a count of ClientMock.ClearProducts invocations
+func (mmClearProducts *ClientMock) ClearProductsBeforeCounter() uint64 {
+	return mm_atomic.LoadUint64(&mmClearProducts.beforeClearProductsCounter)
+}
+
+// Calls returns a list of arguments used in each call to ClientMock.ClearProducts.
+// The list is in the same order as the calls were made (i.e. recent calls have a higher index)
+func (mmClearProducts *mClientMockClearProducts) Calls() []*ClientMockClearProductsParams {
+	mmClearProducts.mutex.RLock()
+
+	argCopy := make([]*ClientMockClearProductsParams, len(mmClearProducts.callArgs))
+	copy(argCopy, mmClearProducts.callArgs)
+
+	mmClearProducts.mutex.RUnlock()
+
+	return argCopy
+}
+
+// MinimockClearProductsDone returns true if the count of the ClearProducts invocations corresponds
+// the number of defined expectations
+func (m *ClientMock) MinimockClearProductsDone() bool {
+	for _, e := range m.ClearProductsMock.expectations {
+		if mm_atomic.LoadUint64(&e.Counter) < 1 {
+			return false
+		}
+	}
+
+	// if default expectation was set then invocations count should be greater than zero
+	if m.ClearProductsMock.defaultExpectation != nil && mm_atomic.LoadUint64(&m.afterClearProductsCounter) < 1 {
+		return false
+	}
+	// if func was set then invocations count should be greater than zero
+	if m.funcClearProducts != nil && mm_atomic.LoadUint64(&m.afterClearProductsCounter) < 1 {
+		return false
+	}
+	return true
+}
+
+// MinimockClearProductsInspect logs each unmet expectation
+func (m *ClientMock) MinimockClearProductsInspect() {
+	for _, e := range m.ClearProductsMock.expectations {
+		if mm_atomic.LoadUint64(&e.Counter) < 1 {
+			m.t.Errorf("Expected call to ClientMock.ClearProducts with params: %#v", *e.params)
+		}
+	}
+
+	// if default expectation was set then invocations count should be greater than zero
+	if m.ClearProductsMock.defaultExpectation != nil && mm_atomic.LoadUint64(&m.afterClearProductsCounter) < 1 {
+		if m.ClearProductsMock.defaultExpectation.params == nil {
+			m.t.Error("Expected call to ClientMock.ClearProducts")
+		} else {
+			m.t.Errorf("Expected call to ClientMock.ClearProducts with params: %#v", *m.ClearProductsMock.defaultExpectation.params)
+		}
+	}
+	// if func was set then invocations count should be greater than zero
+	if m.funcClearProducts != nil && mm_atomic.LoadUint64(&m.afterClearProductsCounter) < 1 {
+		m.t.Error("Expected call to ClientMock.ClearProducts")
+	}
+}
+
 type mClientMockClose struct {
 	mock               *ClientMock
 	defaultExpectation *ClientMockCloseExpectation
@@ -631,14 +864,234 @@ func (m *ClientMock) MinimockGetProductsInfoInspect() {
 	}
 }
 
+type mClientMockInvalidateProducts struct {
+	mock               *ClientMock
+	defaultExpectation *ClientMockInvalidateProductsExpectation
+	expectations       []*ClientMockInvalidateProductsExpectation
+
+	callArgs []*ClientMockInvalidateProductsParams
+	mutex    sync.RWMutex
+}
+
+// ClientMockInvalidateProductsExpectation specifies expectation struct of the Client.InvalidateProducts
+type ClientMockInvalidateProductsExpectation struct {
+	mock    *ClientMock
+	params  *ClientMockInvalidateProductsParams
+	results *ClientMockInvalidateProductsResults
+	Counter uint64
+}
+
+// ClientMockInvalidateProductsParams contains parameters of the Client.InvalidateProducts
+type ClientMockInvalidateProductsParams struct {
+	ctx  context.Context
+	skus []uint32
+}
+
+// ClientMockInvalidateProductsResults contains results of the Client.InvalidateProducts
+type ClientMockInvalidateProductsResults struct {
+	err error
+}
+
+// Expect sets up expected params for Client.InvalidateProducts
+func (mmInvalidateProducts *mClientMockInvalidateProducts) Expect(ctx context.Context, skus []uint32) *mClientMockInvalidateProducts {
+	if mmInvalidateProducts.mock.funcInvalidateProducts != nil {
+		mmInvalidateProducts.mock.t.Fatalf("ClientMock.InvalidateProducts mock is already set by Set")
+	}
+
+	if mmInvalidateProducts.defaultExpectation == nil {
+		mmInvalidateProducts.defaultExpectation = &ClientMockInvalidateProductsExpectation{}
+	}
+
+	mmInvalidateProducts.defaultExpectation.params = &ClientMockInvalidateProductsParams{ctx, skus}
+	for _, e := range mmInvalidateProducts.expectations {
+		if minimock.Equal(e.params, mmInvalidateProducts.defaultExpectation.params) {
+			mmInvalidateProducts.mock.t.Fatalf("Expectation set by When has same params: %#v", *mmInvalidateProducts.defaultExpectation.params)
+		}
+	}
+
+	return mmInvalidateProducts
+}
+
+// Inspect accepts an inspector function that has same arguments as the Client.InvalidateProducts
+func (mmInvalidateProducts *mClientMockInvalidateProducts) Inspect(f func(ctx context.Context, skus []uint32)) *mClientMockInvalidateProducts {
+	if mmInvalidateProducts.mock.inspectFuncInvalidateProducts != nil {
+		mmInvalidateProducts.mock.t.Fatalf("Inspect function is already set for ClientMock.InvalidateProducts")
+	}
+
+	mmInvalidateProducts.mock.inspectFuncInvalidateProducts = f
+
+	return mmInvalidateProducts
+}
+
+// Return sets up results that will be returned by Client.InvalidateProducts
+func (mmInvalidateProducts *mClientMockInvalidateProducts) Return(err error) *ClientMock {
+	if mmInvalidateProducts.mock.funcInvalidateProducts != nil {
+		mmInvalidateProducts.mock.t.Fatalf("ClientMock.InvalidateProducts mock is already set by Set")
+	}
+
+	if mmInvalidateProducts.defaultExpectation == nil {
+		mmInvalidateProducts.defaultExpectation = &ClientMockInvalidateProductsExpectation{mock: mmInvalidateProducts.mock}
+	}
+	mmInvalidateProducts.defaultExpectation.results = &ClientMockInvalidateProductsResults{err}
+	return mmInvalidateProducts.mock
+}
+
+// Set uses given function f to mock the Client.InvalidateProducts method
+func (mmInvalidateProducts *mClientMockInvalidateProducts) Set(f func(ctx context.Context, skus []uint32) (err error)) *ClientMock {
+	if mmInvalidateProducts.defaultExpectation != nil {
+		mmInvalidateProducts.mock.t.Fatalf("Default expectation is already set for the Client.InvalidateProducts method")
+	}
+
+	if len(mmInvalidateProducts.expectations) > 0 {
+		mmInvalidateProducts.mock.t.Fatalf("Some expectations are already set for the Client.InvalidateProducts method")
+	}
+
+	mmInvalidateProducts.mock.funcInvalidateProducts = f
+	return mmInvalidateProducts.mock
+}
+
+// When sets expectation for the Client.InvalidateProducts which will trigger the result defined by the following
+// Then helper
+func (mmInvalidateProducts *mClientMockInvalidateProducts) When(ctx context.Context, skus []uint32) *ClientMockInvalidateProductsExpectation {
+	if mmInvalidateProducts.mock.funcInvalidateProducts != nil {
+		mmInvalidateProducts.mock.t.Fatalf("ClientMock.InvalidateProducts mock is already set by Set")
+	}
+
+	expectation := &ClientMockInvalidateProductsExpectation{
+		mock:   mmInvalidateProducts.mock,
+		params: &ClientMockInvalidateProductsParams{ctx, skus},
+	}
+	mmInvalidateProducts.expectations = append(mmInvalidateProducts.expectations, expectation)
+	return expectation
+}
+
+// Then sets up Client.InvalidateProducts return parameters for the expectation previously defined by the When method
+func (e *ClientMockInvalidateProductsExpectation) Then(err error) *ClientMock {
+	e.results = &ClientMockInvalidateProductsResults{err}
+	return e.mock
+}
+
+// InvalidateProducts implements productsclient.Client
+func (mmInvalidateProducts *ClientMock) InvalidateProducts(ctx context.Context, skus []uint32) (err error) {
+	mm_atomic.AddUint64(&mmInvalidateProducts.beforeInvalidateProductsCounter, 1)
+	defer mm_atomic.AddUint64(&mmInvalidateProducts.afterInvalidateProductsCounter, 1)
+
+	if mmInvalidateProducts.inspectFuncInvalidateProducts != nil {
+		mmInvalidateProducts.inspectFuncInvalidateProducts(ctx, skus)
+	}
+
+	mm_params := &ClientMockInvalidateProductsParams{ctx, skus}
+
+	// Record call args
+	mmInvalidateProducts.InvalidateProductsMock.mutex.Lock()
+	mmInvalidateProducts.InvalidateProductsMock.callArgs = append(mmInvalidateProducts.InvalidateProductsMock.callArgs, mm_params)
+	mmInvalidateProducts.InvalidateProductsMock.mutex.Unlock()
+
+	for _, e := range mmInvalidateProducts.InvalidateProductsMock.expectations {
+		if minimock.Equal(e.params, mm_params) {
+			mm_atomic.AddUint64(&e.Counter, 1)
+			return e.results.err
+		}
+	}
+
+	if mmInvalidateProducts.InvalidateProductsMock.defaultExpectation != nil {
+		mm_atomic.AddUint64(&mmInvalidateProducts.InvalidateProductsMock.defaultExpectation.Counter, 1)
+		mm_want := mmInvalidateProducts.InvalidateProductsMock.defaultExpectation.params
+		mm_got := ClientMockInvalidateProductsParams{ctx, skus}
+		if mm_want != nil && !minimock.Equal(*mm_want, mm_got) {
+			mmInvalidateProducts.t.Errorf("ClientMock.InvalidateProducts got unexpected parameters, want: %#v, got: %#v%s\n", *mm_want, mm_got, minimock.Diff(*mm_want, mm_got))
+		}
+
+		mm_results := mmInvalidateProducts.InvalidateProductsMock.defaultExpectation.results
+		if mm_results == nil {
+			mmInvalidateProducts.t.Fatal("No results are set for the ClientMock.InvalidateProducts")
+		}
+		return (*mm_results).err
+	}
+	if mmInvalidateProducts.funcInvalidateProducts != nil {
+		return mmInvalidateProducts.funcInvalidateProducts(ctx, skus)
+	}
+	mmInvalidateProducts.t.Fatalf("Unexpected call to ClientMock.InvalidateProducts. %v %v", ctx, skus)
+	return
+}
+
+// InvalidateProductsAfterCounter returns a count of finished ClientMock.InvalidateProducts invocations
+func (mmInvalidateProducts *ClientMock) InvalidateProductsAfterCounter() uint64 {
+	return mm_atomic.LoadUint64(&mmInvalidateProducts.afterInvalidateProductsCounter)
+}
+
+// InvalidateProductsBeforeCounter returns a count of ClientMock.InvalidateProducts invocations
+func (mmInvalidateProducts *ClientMock) InvalidateProductsBeforeCounter() uint64 {
+	return mm_atomic.LoadUint64(&mmInvalidateProducts.beforeInvalidateProductsCounter)
+}
+
+// Calls returns a list of arguments used in each call to ClientMock.InvalidateProducts.
+// The list is in the same order as the calls were made (i.e. recent calls have a higher index)
+func (mmInvalidateProducts *mClientMockInvalidateProducts) Calls() []*ClientMockInvalidateProductsParams {
+	mmInvalidateProducts.mutex.RLock()
+
+	argCopy := make([]*ClientMockInvalidateProductsParams, len(mmInvalidateProducts.callArgs))
+	copy(argCopy, mmInvalidateProducts.callArgs)
+
+	mmInvalidateProducts.mutex.RUnlock()
+
+	return argCopy
+}
+
+// MinimockInvalidateProductsDone returns true if the count of the InvalidateProducts invocations corresponds
+// the number of defined expectations
+func (m *ClientMock) MinimockInvalidateProductsDone() bool {
+	for _, e := range m.InvalidateProductsMock.expectations {
+		if mm_atomic.LoadUint64(&e.Counter) < 1 {
+			return false
+		}
+	}
+
+	// if default expectation was set then invocations count should be greater than zero
+	if m.InvalidateProductsMock.defaultExpectation != nil && mm_atomic.LoadUint64(&m.afterInvalidateProductsCounter) < 1 {
+		return false
+	}
+	// if func was set then invocations count should be greater than zero
+	if m.funcInvalidateProducts != nil && mm_atomic.LoadUint64(&m.afterInvalidateProductsCounter) < 1 {
+		return false
+	}
+	return true
+}
+
+// MinimockInvalidateProductsInspect logs each unmet expectation
+func (m *ClientMock) MinimockInvalidateProductsInspect() {
+	for _, e := range m.InvalidateProductsMock.expectations {
+		if mm_atomic.LoadUint64(&e.Counter) < 1 {
+			m.t.Errorf("Expected call to ClientMock.InvalidateProducts with params: %#v", *e.params)
+		}
+	}
+
+	// if default expectation was set then invocations count should be greater than zero
+	if m.InvalidateProductsMock.defaultExpectation != nil && mm_atomic.LoadUint64(&m.afterInvalidateProductsCounter) < 1 {
+		if m.InvalidateProductsMock.defaultExpectation.params == nil {
+			m.t.Error("Expected call to ClientMock.InvalidateProducts")
+		} else {
+			m.t.Errorf("Expected call to ClientMock.InvalidateProducts with params: %#v", *m.InvalidateProductsMock.defaultExpectation.params)
+		}
+	}
+	// if func was set then invocations count should be greater than zero
+	if m.funcInvalidateProducts != nil && mm_atomic.LoadUint64(&m.afterInvalidateProductsCounter) < 1 {
+		m.t.Error("Expected call to ClientMock.InvalidateProducts")
+	}
+}
+
 // MinimockFinish checks that all mocked methods have been called the expected number of times
 func (m *ClientMock) MinimockFinish() {
 	if !m.minimockDone() {
+		m.MinimockClearProductsInspect()
+
 		m.MinimockCloseInspect()
 
 		m.MinimockGetProductInspect()
 
 		m.MinimockGetProductsInfoInspect()
+
+		m.MinimockInvalidateProductsInspect()
 		m.t.FailNow()
 	}
 }
@@ -662,7 +1115,9 @@ func (m *ClientMock) MinimockWait(timeout mm_time.Duration) {
 func (m *ClientMock) minimockDone() bool {
 	done := true
 	return done &&
+		m.MinimockClearProductsDone() &&
 		m.MinimockCloseDone() &&
 		m.MinimockGetProductDone() &&
-		m.MinimockGetProductsInfoDone()
+		m.MinimockGetProductsInfoDone() &&
+		m.MinimockInvalidateProductsDone()
 }
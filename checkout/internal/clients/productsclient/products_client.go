@@ -4,12 +4,17 @@ package productsclient
 //go:generate minimock -i Client -o ./mocks/ -s "_minimock.go"
 
 import (
+	"bufio"
 	"context"
+	"os"
+	"path/filepath"
 	"route256/checkout/internal/config"
 	"route256/checkout/internal/service/model"
 	"route256/libs/cache"
+	"route256/libs/cachebus"
 	"route256/libs/limiter"
 	log "route256/libs/logger"
+	"route256/libs/redis"
 	productServiceAPI "route256/product-service/pkg/product"
 	"sync"
 	"time"
@@ -21,60 +26,223 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 type Client interface {
 	GetProduct(ctx context.Context, sku uint32) (model.Product, error)
 	GetProductsInfo(ctx context.Context, items []model.CartItem) error
+	InvalidateProducts(ctx context.Context, skus []uint32) error
+	ClearProducts(ctx context.Context) error
 	Close() error
 }
 
+// productsCacheName имя кэша товаров в метриках и командах шины сброса кэша
+const productsCacheName = "products"
+
 type client struct {
 	productClient productServiceAPI.ProductServiceClient
 	conn          *grpc.ClientConn
 	token         string
-	rateLimiter   *limiter.Limiter
-	maxConcurrent int
+	rateLimiter   limiter.RateLimiter
+	throttle      *limiter.Adaptive
+	concurrency   *limiter.Semaphore
 	cache         cache.Cache[uint32, model.Product]
+	snapshotPath  string
+	l2            *redis.Client
+	publisher     *cachebus.Publisher[uint32]
+	subscriber    *cachebus.Subscriber[uint32, model.Product]
 }
 
-func New(ctx context.Context, config config.ProductService) Client {
+// New создает клиент product-service
+// Если в rateLimitStore передано хранилище и включен distributedRateLimit, то лимит запросов общий для всех реплик
+func New(ctx context.Context, config config.ProductService, rateLimitStore limiter.Store) Client {
 	conn, err := grpc.Dial(
 		config.Url,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithUnaryInterceptor(otgrpc.OpenTracingClientInterceptor(opentracing.GlobalTracer())),
 	)
 	if err != nil {
-		log.Fatal("failed to connect to product-service server", zap.Error(err))
+		log.Fatal(ctx, "failed to connect to product-service server", zap.Error(err))
 	}
 
 	cacheConfig := cache.Config{
-		MaxSize: config.CacheConfig.MaxSize,
-		TTL:     config.CacheConfig.TTL,
+		MaxSize:         config.CacheConfig.MaxSize,
+		TTL:             config.CacheConfig.TTL,
+		CleanupInterval: config.CacheConfig.CleanupInterval,
+		NegativeTTL:     config.CacheConfig.NegativeTTL,
+		IsNegative:      isNotFound,
+		MaxCost:         config.CacheConfig.MaxCost,
+		Cost:            productCost,
+		RefreshAhead:    config.CacheConfig.RefreshAhead,
+		MaxStale:        config.CacheConfig.MaxStale,
 	}
 	switch config.CacheConfig.Type {
 	case "lru":
 		cacheConfig.Type = cache.LRUCache
 	case "lfu":
 		cacheConfig.Type = cache.LFUCache
+	case "tinylfu":
+		cacheConfig.Type = cache.TinyLFUCache
 	default:
 		cacheConfig.Type = cache.Simple
 	}
-	log.Debug("creating cache with config", zap.Any("cacheConfig", cacheConfig))
-	productsCache, err := cache.NewCache[uint32, model.Product](ctx, cacheConfig)
+	log.Debug(ctx, "creating cache with config", zap.Any("cacheConfig", cacheConfig), zap.Uint("shards", config.CacheConfig.Shards))
+	var productsCache cache.Cache[uint32, model.Product]
+	if config.CacheConfig.Shards > 1 {
+		productsCache, err = cache.NewShardedCache[uint32, model.Product](ctx, cacheConfig, config.CacheConfig.Shards, nil)
+	} else {
+		productsCache, err = cache.NewCache[uint32, model.Product](ctx, cacheConfig)
+	}
+	var l2 *redis.Client
+	var snapshotTime time.Time
+	if err == nil && config.CacheConfig.L2.Addr != "" { // общий для всех реплик checkout второй уровень кэша
+		l2 = redis.New(redis.Options{
+			Addr:     config.CacheConfig.L2.Addr,
+			Password: config.CacheConfig.L2.Password,
+			DB:       config.CacheConfig.L2.DB,
+		})
+		productsCache = cache.NewTieredCache[uint32, model.Product](productsCache, l2, cache.TieredConfig{
+			Prefix:  config.CacheConfig.L2.Prefix,
+			TTL:     config.CacheConfig.L2.TTL,
+			Timeout: time.Duration(config.CacheConfig.L2.TimeoutMs) * time.Millisecond,
+			OnError: func(err error) {
+				log.Warn(ctx, "products cache L2 error", zap.Error(err))
+			},
+		})
+	}
 	if err != nil {
 		log.Error(ctx, "error creating cache", zap.Error(err))
+	} else {
+		registerCollector(cache.NewCollector(productsCacheName, productsCache))
+		snapshotTime = restoreCache(ctx, productsCache, config.CacheConfig.SnapshotPath)
 	}
 
-	return &client{
+	c := &client{
 		productClient: productServiceAPI.NewProductServiceClient(conn),
 		conn:          conn,
 		token:         config.Token,
-		rateLimiter:   limiter.New(ctx, time.Duration(int64(time.Second)/int64(config.RateLimit))),
-		maxConcurrent: int(config.MaxConcurrent),
 		cache:         productsCache,
+		snapshotPath:  config.CacheConfig.SnapshotPath,
+		l2:            l2,
+	}
+	c.rateLimiter, c.throttle = newRateLimiter(config, rateLimitStore)
+	if config.MaxConcurrent > 0 {
+		c.concurrency = limiter.NewSemaphore("product_service", int64(config.MaxConcurrent))
+	}
+	if productsCache != nil {
+		productsCache.SetLoader(c.loadProduct) // для фонового обновления товаров, у которых истекает TTL
+		c.subscribeInvalidation(ctx, config.CacheConfig.Invalidation, snapshotTime)
+	}
+	return c
+}
+
+// newRateLimiter создает лимитер запросов к product-service
+// Квота product-service выдается на токен, поэтому распределенный лимитер использует токен как ключ
+// Если включен adaptiveRateLimit, то лимитер снижает скорость при перегрузке product-service, rateLimit становится верхней границей,
+// адаптивный лимитер возвращается вторым значением для передачи ему результатов запросов
+func newRateLimiter(config config.ProductService, store limiter.Store) (limiter.RateLimiter, *limiter.Adaptive) {
+	var rateLimiter limiter.RateLimiter
+	local := limiter.NewTokenBucket(float64(config.RateLimit), int(config.RateBurst))
+	if config.DistributedRateLimit && store != nil {
+		if config.FallbackRateLimit > 0 {
+			local.SetLimit(float64(config.FallbackRateLimit), int(config.RateBurst))
+		}
+		rateLimiter = limiter.NewDistributed(store, "product-service:"+config.Token, float64(config.RateLimit), int(config.RateBurst), local, 0)
+	} else {
+		rateLimiter = local
 	}
+	if !config.AdaptiveRateLimit.Enabled {
+		return rateLimiter, nil
+	}
+
+	throttle := limiter.NewAdaptive(rateLimiter, int(config.RateBurst), limiter.AdaptiveConfig{
+		MinRate:       config.AdaptiveRateLimit.MinRate,
+		MaxRate:       float64(config.RateLimit),
+		Increase:      config.AdaptiveRateLimit.Increase,
+		Decrease:      config.AdaptiveRateLimit.Decrease,
+		Interval:      time.Duration(config.AdaptiveRateLimit.IntervalMs) * time.Millisecond,
+		TargetLatency: time.Duration(config.AdaptiveRateLimit.TargetLatencyMs) * time.Millisecond,
+		IsOverload:    isOverload,
+	})
+	registerCollector(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "route256",
+		Subsystem: "products_client",
+		Name:      "rate_limit",
+		Help:      "Current adaptive rate limit of product-service requests per second",
+	}, throttle.Rate))
+	return throttle, throttle
+}
+
+// registerCollector регистрирует метрики клиента в реестре Prometheus по умолчанию
+// Метрики, зарегистрированные ранее созданным клиентом, заменяются, поэтому New можно вызывать несколько раз
+func registerCollector(collector prometheus.Collector) {
+	err := prometheus.Register(collector)
+	if existing := (prometheus.AlreadyRegisteredError{}); errors.As(err, &existing) {
+		prometheus.Unregister(existing.ExistingCollector)
+		err = prometheus.Register(collector)
+	}
+	if err != nil {
+		log.Error(context.Background(), "error registering products client metrics", zap.Error(err))
+	}
+}
+
+// subscribeInvalidation подключает кэш к шине сброса кэша, общей для всех реплик checkout
+// Без шины кэш сбрасывается только на той реплике, которая получила запрос
+// Если кэш восстановлен из снимка, то сначала применяются команды, опубликованные после сохранения снимка
+func (c *client) subscribeInvalidation(ctx context.Context, config config.InvalidationConfig, snapshotTime time.Time) {
+	if len(config.Brokers) == 0 {
+		return
+	}
+	busConfig := cachebus.Config{
+		Brokers:           config.Brokers,
+		Topic:             config.Topic,
+		ReplicationFactor: config.ReplicationFactor,
+	}
+	publisher, err := cachebus.NewPublisher[uint32](busConfig, productsCacheName)
+	if err != nil {
+		log.Error(ctx, "error creating products cache invalidation publisher", zap.Error(err))
+		return
+	}
+	subscriber, err := cachebus.NewSubscriber[uint32, model.Product](busConfig, productsCacheName, c.cache)
+	if err != nil {
+		_ = publisher.Close()
+		log.Error(ctx, "error creating products cache invalidation subscriber", zap.Error(err))
+		return
+	}
+	c.publisher = publisher
+	c.subscriber = subscriber
+	go func() {
+		if err := subscriber.Run(ctx, snapshotTime); err != nil {
+			log.Error(ctx, "products cache invalidation subscriber stopped", zap.Error(err))
+		}
+	}()
+}
+
+// isNotFound отбирает ошибки productsService для негативного кэширования
+// Кэшируется только отсутствие товара, остальные ошибки могут быть временными
+func isNotFound(err error) bool {
+	return status.Code(errors.Cause(err)) == codes.NotFound
+}
+
+// isOverload отбирает ошибки productsService, при которых адаптивный лимитер снижает скорость запросов
+func isOverload(err error) bool {
+	switch status.Code(errors.Cause(err)) {
+	case codes.ResourceExhausted, codes.Unavailable:
+		return true
+	}
+	return false
+}
+
+// productRecordOverhead примерный размер записи кэша о товаре в байтах без учета наименования
+const productRecordOverhead = 256
+
+// productCost оценивает размер записи кэша о товаре в байтах для ограничения памяти через maxCost
+func productCost(key, value any) uint64 {
+	product, _ := value.(model.Product)
+	return productRecordOverhead + uint64(len(product.Name))
 }
 
 type ProductRequest struct {
@@ -123,107 +291,252 @@ var (
 	)
 )
 
+// GetProduct возвращает информацию о товаре из кэша, при промахе запрашивает ее в productsService
+// Одновременные промахи по одному и тому же SKU объединяются в один запрос к productsService
+// Промахом в метриках считается только вызов, который сам запросил товар в productsService,
+// вызовы, дождавшиеся чужого запроса, считаются попаданиями
 func (c *client) GetProduct(ctx context.Context, sku uint32) (model.Product, error) {
 	timeStart := time.Now()
-	if c.cache != nil {
-		CacheRequestsCounter.Inc()
-		if result, ok := c.cache.Get(ctx, sku); ok {
-			log.Debug("cache hit for SKU", zap.Uint32("SKU", sku))
-			CacheHitsCounter.Inc()
-			HistogramResponseHitTime.Observe(time.Since(timeStart).Seconds())
-			return *result, nil
+	if c.cache == nil {
+		return c.loadProduct(ctx, sku)
+	}
+	CacheRequestsCounter.Inc()
+	loaded := false
+	result, err := c.cache.GetOrLoad(ctx, sku, func(ctx context.Context, sku uint32) (model.Product, error) {
+		loaded = true
+		log.Debug(ctx, "cache miss for SKU", zap.Uint32("SKU", sku))
+		return c.loadProduct(ctx, sku)
+	})
+	if err != nil {
+		return model.Product{}, err
+	}
+	if loaded {
+		HistogramResponseMissTime.Observe(time.Since(timeStart).Seconds())
+		HistogramResponseTime.Observe(time.Since(timeStart).Seconds())
+	} else {
+		log.Debug(ctx, "cache hit for SKU", zap.Uint32("SKU", sku))
+		CacheHitsCounter.Inc()
+		HistogramResponseHitTime.Observe(time.Since(timeStart).Seconds())
+	}
+	return *result, nil
+}
+
+// loadMissing запрашивает товар, промах по которому уже учтен в кэше, и сохраняет его в кэш
+func (c *client) loadMissing(ctx context.Context, sku uint32, timeStart time.Time) (model.Product, error) {
+	if c.cache == nil {
+		return c.loadProduct(ctx, sku)
+	}
+	result, err := c.cache.Load(ctx, sku, c.loadProduct)
+	if err != nil {
+		return model.Product{}, err
+	}
+	HistogramResponseMissTime.Observe(time.Since(timeStart).Seconds())
+	HistogramResponseTime.Observe(time.Since(timeStart).Seconds())
+	return *result, nil
+}
+
+// loadProduct запрашивает информацию о товаре в productsService с учетом рейт лимита
+// Количество одновременных запросов ограничено параметром maxConcurrent общим для всех запросов к checkout
+func (c *client) loadProduct(ctx context.Context, sku uint32) (model.Product, error) {
+	if c.concurrency != nil {
+		if err := c.concurrency.Acquire(ctx, 1); err != nil {
+			return model.Product{}, errors.WithMessage(err, "getProduct request cancelled")
 		}
-		log.Debug("cache miss for SKU", zap.Uint32("SKU", sku))
+		defer c.concurrency.Release(1)
 	}
-	select {
-	case <-ctx.Done():
-		return model.Product{}, errors.New("getProduct request cancelled")
-	case t := <-c.rateLimiter.C:
-		log.Debug("getProduct at time", zap.String("time", t.Format("2006-01-02 15:04:05.000000")))
+	if err := c.rateLimiter.Wait(ctx); err != nil {
+		return model.Product{}, errors.WithMessage(err, "getProduct request cancelled")
 	}
+	log.Debug(ctx, "getProduct at time", zap.String("time", time.Now().Format("2006-01-02 15:04:05.000000")))
 	request := productServiceAPI.GetProductRequest{
 		Token: c.token,
 		Sku:   sku,
 	}
 
+	requestStart := time.Now()
 	response, err := c.productClient.GetProduct(ctx, &request)
+	if c.throttle != nil && ctx.Err() == nil {
+		c.throttle.Observe(time.Since(requestStart), err)
+	}
 	if err != nil {
 		return model.Product{}, errors.Wrap(err, "making loms.getProduct gRPC request")
 	}
 
-	result := model.Product{
+	log.Debug(ctx, "product info loaded for SKU", zap.Uint32("SKU", sku))
+	return model.Product{
 		Name:  response.Name,
 		Price: response.Price,
-	}
-
-	if c.cache != nil {
-		log.Debug("saving response to cache for SKU", zap.Uint32("SKU", sku))
-		if !c.cache.Set(ctx, sku, result) {
-			log.Info("can't save products.GetProduct request result in cache for", zap.Uint32("SKU", sku))
-		} else {
-			log.Debug("response saved to cache for SKU", zap.Uint32("SKU", sku))
-		}
-		HistogramResponseMissTime.Observe(time.Since(timeStart).Seconds())
-		HistogramResponseTime.Observe(time.Since(timeStart).Seconds())
-	}
-	return result, nil
+	}, nil
 }
 
-// GetProductsInfo параллельное выполнение запросов к productsService для заполнения информации о товарах в корзине
-// Максимальное количество одновременных запросов задается через конфигурацию, параметр maxConcurrent для сервиса
-// Если параметр равен 0, то все товары запрашиваются параллельно без ограничений
+// GetProductsInfo заполняет информацию о товарах в корзине
+// Товары из кэша берутся одним запросом к кэшу, остальные параллельно запрашиваются в productsService
+// Максимальное количество одновременных запросов задается через конфигурацию, параметр maxConcurrent для сервиса,
+// ограничение общее для всех запросов к checkout. Если параметр равен 0, то все товары запрашиваются параллельно без ограничений
 func (c *client) GetProductsInfo(ctx context.Context, items []model.CartItem) error {
-	var errs error
-	taskSource := make(chan *model.CartItem)
-
-	concurrency := c.maxConcurrent
-	if c.maxConcurrent == 0 || len(items) <= c.maxConcurrent {
-		concurrency = len(items)
+	timeStart := time.Now()
+	missing := c.fillFromCache(ctx, items)
+	if len(missing) == 0 {
+		return nil
 	}
+
+	var errs error
+	var errsLock sync.Mutex
 	var wg sync.WaitGroup
-	for i := 0; i < concurrency; i++ {
+	for _, item := range missing {
 		wg.Add(1)
-		go func(ctx context.Context, taskNum int) {
+		go func(item *model.CartItem) {
 			defer wg.Done()
-			log.Debug("task started", zap.Int("taskNum", taskNum))
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case item := <-taskSource:
-					if item == nil {
-						log.Debug("taskSource closed, task finishing", zap.Int("taskNum", taskNum))
-						return
-					}
-					log.Debug("task requesting info for sku", zap.Int("taskNum", taskNum), zap.Uint32("SKU", item.SKU))
-					product, err := c.GetProduct(ctx, item.SKU)
-					if err != nil {
-						if errs == nil {
-							errs = err
-						} else {
-							errs = errors.WithMessage(errs, err.Error())
-						}
-					}
-					item.Name = product.Name
-					item.Price = product.Price
+			log.Debug(ctx, "requesting info for sku", zap.Uint32("SKU", item.SKU))
+			product, err := c.loadMissing(ctx, item.SKU, timeStart)
+			if err != nil {
+				errsLock.Lock()
+				if errs == nil {
+					errs = err
+				} else {
+					errs = errors.WithMessage(errs, err.Error())
 				}
+				errsLock.Unlock()
+				return
 			}
-		}(ctx, i)
+			item.Name = product.Name
+			item.Price = product.Price
+		}(item)
 	}
+	wg.Wait()
+
+	return errs
+}
+
+// fillFromCache заполняет информацию о товарах, найденных в кэше, возвращает товары, которых в кэше нет
+func (c *client) fillFromCache(ctx context.Context, items []model.CartItem) []*model.CartItem {
+	missing := make([]*model.CartItem, 0, len(items))
+	if c.cache == nil {
+		for i := range items {
+			missing = append(missing, &items[i])
+		}
+		return missing
+	}
+
+	timeStart := time.Now()
+	skus := make([]uint32, 0, len(items))
+	for _, item := range items {
+		skus = append(skus, item.SKU)
+	}
+	CacheRequestsCounter.Add(float64(len(skus)))
+	found, _ := c.cache.GetMany(ctx, skus)
+	CacheHitsCounter.Add(float64(len(found)))
 	for i := range items {
-		select {
-		case <-ctx.Done():
-			break
-		case taskSource <- &items[i]:
+		product, ok := found[items[i].SKU]
+		if !ok {
+			missing = append(missing, &items[i])
+			continue
 		}
+		items[i].Name = product.Name
+		items[i].Price = product.Price
+		HistogramResponseHitTime.Observe(time.Since(timeStart).Seconds())
 	}
-	close(taskSource)
-	wg.Wait()
+	log.Debug(ctx, "products found in cache", zap.Int("hits", len(items)-len(missing)), zap.Int("misses", len(missing)))
+	return missing
+}
 
-	return errs
+// InvalidateProducts удаляет товары из кэша на всех репликах, например после изменения цены
+func (c *client) InvalidateProducts(ctx context.Context, skus []uint32) error {
+	if c.cache == nil {
+		return nil
+	}
+	for _, sku := range skus {
+		c.cache.Invalidate(ctx, sku)
+	}
+	if c.publisher == nil {
+		return nil
+	}
+	return errors.WithMessage(c.publisher.Invalidate(ctx, skus...), "publishing products invalidation")
+}
+
+// ClearProducts удаляет все товары из кэша на всех репликах
+func (c *client) ClearProducts(ctx context.Context) error {
+	if c.cache == nil {
+		return nil
+	}
+	if err := c.cache.Clear(ctx); err != nil {
+		return errors.WithMessage(err, "clearing products cache")
+	}
+	if c.publisher == nil {
+		return nil
+	}
+	return errors.WithMessage(c.publisher.Clear(ctx), "publishing products cache clear")
 }
 
 func (c *client) Close() error {
-	c.rateLimiter.Stop()
+	if c.cache != nil {
+		_ = c.cache.Close()
+		if err := saveCache(c.cache, c.snapshotPath); err != nil {
+			log.Error(context.Background(), "error saving products cache snapshot", zap.Error(err))
+		}
+	}
+	if c.l2 != nil {
+		_ = c.l2.Close()
+	}
+	if c.subscriber != nil {
+		_ = c.subscriber.Close()
+		_ = c.publisher.Close()
+	}
 	return c.conn.Close()
 }
+
+// restoreCache загружает в кэш товары, сохраненные при предыдущей остановке сервиса, и возвращает время сохранения снимка
+// Отсутствие файла не ошибка: при первом запуске снимка еще нет, в этом случае возвращается нулевое время
+func restoreCache(ctx context.Context, productsCache cache.Cache[uint32, model.Product], path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return time.Time{}
+	}
+	if err != nil {
+		log.Error(ctx, "error opening products cache snapshot", zap.Error(err))
+		return time.Time{}
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		log.Error(ctx, "error reading products cache snapshot info", zap.Error(err))
+		return time.Time{}
+	}
+
+	if err := productsCache.Restore(bufio.NewReader(file)); err != nil {
+		log.Error(ctx, "error restoring products cache snapshot", zap.Error(err))
+		return time.Time{}
+	}
+	log.Info(ctx, "products cache restored from snapshot", zap.String("path", path), zap.Uint64("size", productsCache.Stats().Size))
+	return info.ModTime()
+}
+
+// saveCache сохраняет снимок кэша товаров в файл
+// Снимок пишется во временный файл и переименовывается, чтобы при сбое не испортить предыдущий снимок
+func saveCache(productsCache cache.Cache[uint32, model.Product], path string) error {
+	if path == "" {
+		return nil
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return errors.Wrap(err, "creating snapshot file")
+	}
+	defer os.Remove(file.Name())
+
+	writer := bufio.NewWriter(file)
+	if err := productsCache.Snapshot(writer); err != nil {
+		_ = file.Close()
+		return errors.WithMessage(err, "writing snapshot")
+	}
+	if err := writer.Flush(); err != nil {
+		_ = file.Close()
+		return errors.Wrap(err, "writing snapshot")
+	}
+	if err := file.Close(); err != nil {
+		return errors.Wrap(err, "closing snapshot file")
+	}
+	return errors.Wrap(os.Rename(file.Name(), path), "renaming snapshot file")
+}
@@ -2,29 +2,70 @@ package config
 
 import (
 	"os"
+	"route256/libs/interceptors"
 
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
 )
 
 type CacheConfig struct {
-	MaxSize uint64 `yaml:"maxSize"`
-	Type    string `yaml:"type"`
-	TTL     uint64 `yaml:"ttl"`
+	MaxSize         uint64             `yaml:"maxSize"`
+	Type            string             `yaml:"type"`
+	TTL             uint64             `yaml:"ttl"`
+	CleanupInterval uint64             `yaml:"cleanupInterval"`
+	Shards          uint               `yaml:"shards"`
+	NegativeTTL     uint64             `yaml:"negativeTTL"`
+	MaxCost         uint64             `yaml:"maxCost"`
+	RefreshAhead    uint64             `yaml:"refreshAhead"`
+	MaxStale        uint64             `yaml:"maxStale"`
+	SnapshotPath    string             `yaml:"snapshotPath"`
+	L2              L2Config           `yaml:"l2"`
+	Invalidation    InvalidationConfig `yaml:"invalidation"`
+}
+
+type InvalidationConfig struct {
+	Brokers           []string `yaml:"brokers"`
+	Topic             string   `yaml:"topic"`
+	ReplicationFactor int16    `yaml:"replicationFactor"`
+}
+
+type L2Config struct {
+	Addr      string `yaml:"addr"`
+	Password  string `yaml:"password"`
+	DB        int    `yaml:"db"`
+	Prefix    string `yaml:"prefix"`
+	TTL       uint64 `yaml:"ttl"`
+	TimeoutMs uint64 `yaml:"timeoutMs"`
 }
 
 type ProductService struct {
-	Url           string      `yaml:"url"`
-	Token         string      `yaml:"token"`
-	RateLimit     uint32      `yaml:"rateLimit"`
-	MaxConcurrent uint32      `yaml:"maxConcurrent"`
-	UseCache      bool        `yaml:"useCache"`
-	CacheConfig   CacheConfig `yaml:"cacheConfig"`
+	Url                  string            `yaml:"url"`
+	Token                string            `yaml:"token"`
+	RateLimit            uint32            `yaml:"rateLimit"`
+	RateBurst            uint32            `yaml:"rateBurst"`
+	DistributedRateLimit bool              `yaml:"distributedRateLimit"`
+	FallbackRateLimit    uint32            `yaml:"fallbackRateLimit"`
+	AdaptiveRateLimit    AdaptiveRateLimit `yaml:"adaptiveRateLimit"`
+	MaxConcurrent        uint32            `yaml:"maxConcurrent"`
+	UseCache             bool              `yaml:"useCache"`
+	CacheConfig          CacheConfig       `yaml:"cacheConfig"`
+}
+
+type AdaptiveRateLimit struct {
+	Enabled         bool    `yaml:"enabled"`
+	MinRate         float64 `yaml:"minRate"`
+	Increase        float64 `yaml:"increase"`
+	Decrease        float64 `yaml:"decrease"`
+	IntervalMs      uint64  `yaml:"intervalMs"`
+	TargetLatencyMs uint64  `yaml:"targetLatencyMs"`
 }
 
 type ConfigStruct struct {
-	Token    string `yaml:"token"`
-	Services struct {
+	Token      string                       `yaml:"token"`
+	AdminToken string                       `yaml:"adminToken"` // Токен административных методов gRPC и HTTP API, если пустой, то методы gRPC отключены, а HTTP API изменяет состояние только с localhost
+	LogLevel   string                       `yaml:"logLevel"`
+	RateLimit  interceptors.RateLimitConfig `yaml:"rateLimit"`
+	Services   struct {
 		Loms           string         `yaml:"loms"`
 		ProductService ProductService `yaml:"productService"`
 	} `yaml:"services"`
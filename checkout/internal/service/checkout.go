@@ -13,6 +13,8 @@ type LOMSClient interface {
 type ProductClient interface {
 	GetProduct(ctx context.Context, sku uint32) (model.Product, error)
 	GetProductsInfo(ctx context.Context, items []model.CartItem) error
+	InvalidateProducts(ctx context.Context, skus []uint32) error
+	ClearProducts(ctx context.Context) error
 }
 
 type CartRepository interface {
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrNothingToInvalidate = errors.New("no SKUs to invalidate")
+)
+
+// InvalidateProductsCache сбрасывает кэш товаров на всех репликах: указанные товары или весь кэш, если all
+func (m *Service) InvalidateProductsCache(ctx context.Context, skus []uint32, all bool) error {
+	if all {
+		return m.ProductService.ClearProducts(ctx)
+	}
+	if len(skus) == 0 {
+		return ErrNothingToInvalidate
+	}
+	return m.ProductService.InvalidateProducts(ctx, skus)
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	lomsClientMocks "route256/checkout/internal/clients/lomsclient/mocks"
+	productsClient "route256/checkout/internal/clients/productsclient"
+	productsClientMocks "route256/checkout/internal/clients/productsclient/mocks"
+	cartRepoMocks "route256/checkout/internal/repository/postgres/mocks"
+	"testing"
+
+	"github.com/gojuno/minimock/v3"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvalidateProductsCache(t *testing.T) {
+	type productsClientMockFunc func(mc *minimock.Controller) productsClient.Client
+
+	type args struct {
+		ctx  context.Context
+		skus []uint32
+		all  bool
+	}
+
+	var (
+		mc  = minimock.NewController(t)
+		ctx = context.Background()
+
+		skus = []uint32{5097510, 1076963}
+
+		publishError = errors.New("publishing products invalidation")
+	)
+
+	tests := []struct {
+		name               string
+		args               args
+		err                error
+		productsClientMock productsClientMockFunc
+	}{
+		{
+			name: "invalidate SKUs",
+			args: args{
+				ctx:  ctx,
+				skus: skus,
+			},
+			err: nil,
+			productsClientMock: func(mc *minimock.Controller) productsClient.Client {
+				mock := productsClientMocks.NewClientMock(mc)
+				mock.InvalidateProductsMock.Expect(ctx, skus).Return(nil)
+				return mock
+			},
+		},
+		{
+			name: "clear all",
+			args: args{
+				ctx:  ctx,
+				skus: skus,
+				all:  true,
+			},
+			err: nil,
+			productsClientMock: func(mc *minimock.Controller) productsClient.Client {
+				mock := productsClientMocks.NewClientMock(mc)
+				mock.ClearProductsMock.Expect(ctx).Return(nil)
+				return mock
+			},
+		},
+		{
+			name: "no SKUs",
+			args: args{
+				ctx: ctx,
+			},
+			err: ErrNothingToInvalidate,
+			productsClientMock: func(mc *minimock.Controller) productsClient.Client {
+				mock := productsClientMocks.NewClientMock(mc)
+				return mock
+			},
+		},
+		{
+			name: "publish error",
+			args: args{
+				ctx:  ctx,
+				skus: skus,
+			},
+			err: publishError,
+			productsClientMock: func(mc *minimock.Controller) productsClient.Client {
+				mock := productsClientMocks.NewClientMock(mc)
+				mock.InvalidateProductsMock.Expect(ctx, skus).Return(publishError)
+				return mock
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := New(lomsClientMocks.NewClientMock(mc), tt.productsClientMock(mc), cartRepoMocks.NewCartRepoMock(mc))
+
+			err := service.InvalidateProductsCache(tt.args.ctx, tt.args.skus, tt.args.all)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -3,8 +3,10 @@ package service
 import (
 	"context"
 	"route256/checkout/internal/service/model"
+	log "route256/libs/logger"
 
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 )
 
 var (
@@ -28,6 +30,8 @@ func (m *Service) Purchase(ctx context.Context, user int64) (int64, error) {
 	if err != nil {
 		return -1, errors.WithMessage(err, "creating order")
 	}
+	ctx = log.With(ctx, zap.Int64("orderID", orderNo))
+	log.Info(ctx, "order created")
 
 	if err := m.CartRepo.CleanCart(ctx, user); err != nil {
 		return -1, errors.WithMessage(err, "cleaning cart")
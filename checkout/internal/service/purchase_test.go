@@ -9,12 +9,14 @@ import (
 	cartRepo "route256/checkout/internal/repository/postgres"
 	cartRepoMocks "route256/checkout/internal/repository/postgres/mocks"
 	"route256/checkout/internal/service/model"
+	log "route256/libs/logger"
 	"testing"
 
 	"github.com/brianvoe/gofakeit/v6"
 	"github.com/gojuno/minimock/v3"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 func TestPurchase(t *testing.T) {
@@ -43,7 +45,8 @@ func TestPurchase(t *testing.T) {
 			Items: items,
 		}
 
-		orderID = gofakeit.Int64()
+		orderID  = gofakeit.Int64()
+		orderCtx = log.With(ctx, zap.Int64("orderID", orderID)) // Purchase adds ID of created order to log fields
 
 		cartRepoError  = errors.New("getting cart from db")
 		cartCleanError = errors.New("cleaning cart")
@@ -70,7 +73,7 @@ func TestPurchase(t *testing.T) {
 			cartRepoMock: func(mc *minimock.Controller) cartRepo.CartRepo {
 				mock := cartRepoMocks.NewCartRepoMock(mc)
 				mock.GetCartMock.Expect(ctx, userID).Return(items, nil)
-				mock.CleanCartMock.Expect(ctx, userID).Return(nil)
+				mock.CleanCartMock.Expect(orderCtx, userID).Return(nil)
 				return mock
 			},
 			lomsClientMock: func(mc *minimock.Controller) lomsClient.Client {
@@ -161,7 +164,7 @@ func TestPurchase(t *testing.T) {
 			cartRepoMock: func(mc *minimock.Controller) cartRepo.CartRepo {
 				mock := cartRepoMocks.NewCartRepoMock(mc)
 				mock.GetCartMock.Expect(ctx, userID).Return(items, nil)
-				mock.CleanCartMock.Expect(ctx, userID).Return(cartCleanError)
+				mock.CleanCartMock.Expect(orderCtx, userID).Return(cartCleanError)
 				return mock
 			},
 			lomsClientMock: func(mc *minimock.Controller) lomsClient.Client {
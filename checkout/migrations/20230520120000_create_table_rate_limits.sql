@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS rate_limits (
+  key text NOT NULL PRIMARY KEY,
+  tat timestamptz NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS rate_limits;
+-- +goose StatementEnd
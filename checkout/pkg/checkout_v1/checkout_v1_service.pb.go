@@ -514,6 +514,103 @@ func (x *PurchaseResponse) GetOrderID() int64 {
 	return 0
 }
 
+// Запрос на сброс кэша товаров
+type InvalidateProductsCacheRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Коды товаров, которые нужно удалить из кэша
+	Skus []uint32 `protobuf:"varint,1,rep,packed,name=skus,proto3" json:"skus,omitempty"`
+	// Удалить из кэша все товары
+	All bool `protobuf:"varint,2,opt,name=all,proto3" json:"all,omitempty"`
+}
+
+func (x *InvalidateProductsCacheRequest) Reset() {
+	*x = InvalidateProductsCacheRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_checkout_v1_service_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *InvalidateProductsCacheRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InvalidateProductsCacheRequest) ProtoMessage() {}
+
+func (x *InvalidateProductsCacheRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_checkout_v1_service_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InvalidateProductsCacheRequest.ProtoReflect.Descriptor instead.
+func (*InvalidateProductsCacheRequest) Descriptor() ([]byte, []int) {
+	return file_checkout_v1_service_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *InvalidateProductsCacheRequest) GetSkus() []uint32 {
+	if x != nil {
+		return x.Skus
+	}
+	return nil
+}
+
+func (x *InvalidateProductsCacheRequest) GetAll() bool {
+	if x != nil {
+		return x.All
+	}
+	return false
+}
+
+// Ответ на запрос на сброс кэша товаров
+type InvalidateProductsCacheResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *InvalidateProductsCacheResponse) Reset() {
+	*x = InvalidateProductsCacheResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_checkout_v1_service_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *InvalidateProductsCacheResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InvalidateProductsCacheResponse) ProtoMessage() {}
+
+func (x *InvalidateProductsCacheResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_checkout_v1_service_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InvalidateProductsCacheResponse.ProtoReflect.Descriptor instead.
+func (*InvalidateProductsCacheResponse) Descriptor() ([]byte, []int) {
+	return file_checkout_v1_service_proto_rawDescGZIP(), []int{10}
+}
+
 var File_checkout_v1_service_proto protoreflect.FileDescriptor
 
 var file_checkout_v1_service_proto_rawDesc = []byte{
@@ -553,36 +650,51 @@ var file_checkout_v1_service_proto_rawDesc = []byte{
 	0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x75, 0x73, 0x65, 0x72, 0x22, 0x2c, 0x0a, 0x10, 0x50,
 	0x75, 0x72, 0x63, 0x68, 0x61, 0x73, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
 	0x18, 0x0a, 0x07, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03,
-	0x52, 0x07, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x49, 0x44, 0x32, 0x92, 0x03, 0x0a, 0x0f, 0x43, 0x68,
-	0x65, 0x63, 0x6b, 0x6f, 0x75, 0x74, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x5c, 0x0a,
-	0x09, 0x41, 0x64, 0x64, 0x54, 0x6f, 0x43, 0x61, 0x72, 0x74, 0x12, 0x26, 0x2e, 0x72, 0x6f, 0x75,
-	0x74, 0x65, 0x32, 0x35, 0x36, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x6f, 0x75, 0x74, 0x5f, 0x76,
-	0x31, 0x2e, 0x41, 0x64, 0x64, 0x54, 0x6f, 0x43, 0x61, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x1a, 0x27, 0x2e, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x32, 0x35, 0x36, 0x2e, 0x63, 0x68,
-	0x65, 0x63, 0x6b, 0x6f, 0x75, 0x74, 0x5f, 0x76, 0x31, 0x2e, 0x41, 0x64, 0x64, 0x54, 0x6f, 0x43,
-	0x61, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x6b, 0x0a, 0x0e, 0x44,
-	0x65, 0x6c, 0x65, 0x74, 0x65, 0x46, 0x72, 0x6f, 0x6d, 0x43, 0x61, 0x72, 0x74, 0x12, 0x2b, 0x2e,
+	0x52, 0x07, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x49, 0x44, 0x22, 0x46, 0x0a, 0x1e, 0x49, 0x6e, 0x76,
+	0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x50, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x73, 0x43,
+	0x61, 0x63, 0x68, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x73,
+	0x6b, 0x75, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0d, 0x52, 0x04, 0x73, 0x6b, 0x75, 0x73, 0x12,
+	0x10, 0x0a, 0x03, 0x61, 0x6c, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x03, 0x61, 0x6c,
+	0x6c, 0x22, 0x21, 0x0a, 0x1f, 0x49, 0x6e, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x50,
+	0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x73, 0x43, 0x61, 0x63, 0x68, 0x65, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x32, 0x9b, 0x04, 0x0a, 0x0f, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x6f, 0x75,
+	0x74, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x5c, 0x0a, 0x09, 0x41, 0x64, 0x64, 0x54,
+	0x6f, 0x43, 0x61, 0x72, 0x74, 0x12, 0x26, 0x2e, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x32, 0x35, 0x36,
+	0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x6f, 0x75, 0x74, 0x5f, 0x76, 0x31, 0x2e, 0x41, 0x64, 0x64,
+	0x54, 0x6f, 0x43, 0x61, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x27, 0x2e,
 	0x72, 0x6f, 0x75, 0x74, 0x65, 0x32, 0x35, 0x36, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x6f, 0x75,
-	0x74, 0x5f, 0x76, 0x31, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x46, 0x72, 0x6f, 0x6d, 0x43,
-	0x61, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x2c, 0x2e, 0x72, 0x6f, 0x75,
-	0x74, 0x65, 0x32, 0x35, 0x36, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x6f, 0x75, 0x74, 0x5f, 0x76,
-	0x31, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x46, 0x72, 0x6f, 0x6d, 0x43, 0x61, 0x72, 0x74,
-	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x59, 0x0a, 0x08, 0x4c, 0x69, 0x73, 0x74,
-	0x43, 0x61, 0x72, 0x74, 0x12, 0x25, 0x2e, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x32, 0x35, 0x36, 0x2e,
-	0x63, 0x68, 0x65, 0x63, 0x6b, 0x6f, 0x75, 0x74, 0x5f, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73, 0x74,
-	0x43, 0x61, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x26, 0x2e, 0x72, 0x6f,
-	0x75, 0x74, 0x65, 0x32, 0x35, 0x36, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x6f, 0x75, 0x74, 0x5f,
-	0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x43, 0x61, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x12, 0x59, 0x0a, 0x08, 0x50, 0x75, 0x72, 0x63, 0x68, 0x61, 0x73, 0x65, 0x12,
+	0x74, 0x5f, 0x76, 0x31, 0x2e, 0x41, 0x64, 0x64, 0x54, 0x6f, 0x43, 0x61, 0x72, 0x74, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x6b, 0x0a, 0x0e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65,
+	0x46, 0x72, 0x6f, 0x6d, 0x43, 0x61, 0x72, 0x74, 0x12, 0x2b, 0x2e, 0x72, 0x6f, 0x75, 0x74, 0x65,
+	0x32, 0x35, 0x36, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x6f, 0x75, 0x74, 0x5f, 0x76, 0x31, 0x2e,
+	0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x46, 0x72, 0x6f, 0x6d, 0x43, 0x61, 0x72, 0x74, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x2c, 0x2e, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x32, 0x35, 0x36,
+	0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x6f, 0x75, 0x74, 0x5f, 0x76, 0x31, 0x2e, 0x44, 0x65, 0x6c,
+	0x65, 0x74, 0x65, 0x46, 0x72, 0x6f, 0x6d, 0x43, 0x61, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x59, 0x0a, 0x08, 0x4c, 0x69, 0x73, 0x74, 0x43, 0x61, 0x72, 0x74, 0x12,
 	0x25, 0x2e, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x32, 0x35, 0x36, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b,
-	0x6f, 0x75, 0x74, 0x5f, 0x76, 0x31, 0x2e, 0x50, 0x75, 0x72, 0x63, 0x68, 0x61, 0x73, 0x65, 0x52,
+	0x6f, 0x75, 0x74, 0x5f, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x43, 0x61, 0x72, 0x74, 0x52,
 	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x26, 0x2e, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x32, 0x35,
-	0x36, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x6f, 0x75, 0x74, 0x5f, 0x76, 0x31, 0x2e, 0x50, 0x75,
-	0x72, 0x63, 0x68, 0x61, 0x73, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x2f,
-	0x5a, 0x2d, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x32, 0x35, 0x36, 0x2f, 0x63, 0x68, 0x65, 0x63, 0x6b,
-	0x6f, 0x75, 0x74, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x6f, 0x75, 0x74,
-	0x5f, 0x76, 0x31, 0x3b, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x6f, 0x75, 0x74, 0x5f, 0x76, 0x31, 0x62,
-	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x36, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x6f, 0x75, 0x74, 0x5f, 0x76, 0x31, 0x2e, 0x4c, 0x69,
+	0x73, 0x74, 0x43, 0x61, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x59,
+	0x0a, 0x08, 0x50, 0x75, 0x72, 0x63, 0x68, 0x61, 0x73, 0x65, 0x12, 0x25, 0x2e, 0x72, 0x6f, 0x75,
+	0x74, 0x65, 0x32, 0x35, 0x36, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x6f, 0x75, 0x74, 0x5f, 0x76,
+	0x31, 0x2e, 0x50, 0x75, 0x72, 0x63, 0x68, 0x61, 0x73, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x26, 0x2e, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x32, 0x35, 0x36, 0x2e, 0x63, 0x68, 0x65,
+	0x63, 0x6b, 0x6f, 0x75, 0x74, 0x5f, 0x76, 0x31, 0x2e, 0x50, 0x75, 0x72, 0x63, 0x68, 0x61, 0x73,
+	0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x86, 0x01, 0x0a, 0x17, 0x49, 0x6e,
+	0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x50, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x73,
+	0x43, 0x61, 0x63, 0x68, 0x65, 0x12, 0x34, 0x2e, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x32, 0x35, 0x36,
+	0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x6f, 0x75, 0x74, 0x5f, 0x76, 0x31, 0x2e, 0x49, 0x6e, 0x76,
+	0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x50, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x73, 0x43,
+	0x61, 0x63, 0x68, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x35, 0x2e, 0x72, 0x6f,
+	0x75, 0x74, 0x65, 0x32, 0x35, 0x36, 0x2e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x6f, 0x75, 0x74, 0x5f,
+	0x76, 0x31, 0x2e, 0x49, 0x6e, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x50, 0x72, 0x6f,
+	0x64, 0x75, 0x63, 0x74, 0x73, 0x43, 0x61, 0x63, 0x68, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x42, 0x2f, 0x5a, 0x2d, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x32, 0x35, 0x36, 0x2f, 0x63,
+	0x68, 0x65, 0x63, 0x6b, 0x6f, 0x75, 0x74, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x63, 0x68, 0x65, 0x63,
+	0x6b, 0x6f, 0x75, 0x74, 0x5f, 0x76, 0x31, 0x3b, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x6f, 0x75, 0x74,
+	0x5f, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -597,33 +709,37 @@ func file_checkout_v1_service_proto_rawDescGZIP() []byte {
 	return file_checkout_v1_service_proto_rawDescData
 }
 
-var file_checkout_v1_service_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_checkout_v1_service_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
 var file_checkout_v1_service_proto_goTypes = []interface{}{
-	(*AddToCartRequest)(nil),       // 0: route256.checkout_v1.AddToCartRequest
-	(*AddToCartResponse)(nil),      // 1: route256.checkout_v1.AddToCartResponse
-	(*DeleteFromCartRequest)(nil),  // 2: route256.checkout_v1.DeleteFromCartRequest
-	(*DeleteFromCartResponse)(nil), // 3: route256.checkout_v1.DeleteFromCartResponse
-	(*ListCartRequest)(nil),        // 4: route256.checkout_v1.ListCartRequest
-	(*CartItem)(nil),               // 5: route256.checkout_v1.CartItem
-	(*ListCartResponse)(nil),       // 6: route256.checkout_v1.ListCartResponse
-	(*PurchaseRequest)(nil),        // 7: route256.checkout_v1.PurchaseRequest
-	(*PurchaseResponse)(nil),       // 8: route256.checkout_v1.PurchaseResponse
+	(*AddToCartRequest)(nil),                // 0: route256.checkout_v1.AddToCartRequest
+	(*AddToCartResponse)(nil),               // 1: route256.checkout_v1.AddToCartResponse
+	(*DeleteFromCartRequest)(nil),           // 2: route256.checkout_v1.DeleteFromCartRequest
+	(*DeleteFromCartResponse)(nil),          // 3: route256.checkout_v1.DeleteFromCartResponse
+	(*ListCartRequest)(nil),                 // 4: route256.checkout_v1.ListCartRequest
+	(*CartItem)(nil),                        // 5: route256.checkout_v1.CartItem
+	(*ListCartResponse)(nil),                // 6: route256.checkout_v1.ListCartResponse
+	(*PurchaseRequest)(nil),                 // 7: route256.checkout_v1.PurchaseRequest
+	(*PurchaseResponse)(nil),                // 8: route256.checkout_v1.PurchaseResponse
+	(*InvalidateProductsCacheRequest)(nil),  // 9: route256.checkout_v1.InvalidateProductsCacheRequest
+	(*InvalidateProductsCacheResponse)(nil), // 10: route256.checkout_v1.InvalidateProductsCacheResponse
 }
 var file_checkout_v1_service_proto_depIdxs = []int32{
-	5, // 0: route256.checkout_v1.ListCartResponse.items:type_name -> route256.checkout_v1.CartItem
-	0, // 1: route256.checkout_v1.CheckoutService.AddToCart:input_type -> route256.checkout_v1.AddToCartRequest
-	2, // 2: route256.checkout_v1.CheckoutService.DeleteFromCart:input_type -> route256.checkout_v1.DeleteFromCartRequest
-	4, // 3: route256.checkout_v1.CheckoutService.ListCart:input_type -> route256.checkout_v1.ListCartRequest
-	7, // 4: route256.checkout_v1.CheckoutService.Purchase:input_type -> route256.checkout_v1.PurchaseRequest
-	1, // 5: route256.checkout_v1.CheckoutService.AddToCart:output_type -> route256.checkout_v1.AddToCartResponse
-	3, // 6: route256.checkout_v1.CheckoutService.DeleteFromCart:output_type -> route256.checkout_v1.DeleteFromCartResponse
-	6, // 7: route256.checkout_v1.CheckoutService.ListCart:output_type -> route256.checkout_v1.ListCartResponse
-	8, // 8: route256.checkout_v1.CheckoutService.Purchase:output_type -> route256.checkout_v1.PurchaseResponse
-	5, // [5:9] is the sub-list for method output_type
-	1, // [1:5] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	5,  // 0: route256.checkout_v1.ListCartResponse.items:type_name -> route256.checkout_v1.CartItem
+	0,  // 1: route256.checkout_v1.CheckoutService.AddToCart:input_type -> route256.checkout_v1.AddToCartRequest
+	2,  // 2: route256.checkout_v1.CheckoutService.DeleteFromCart:input_type -> route256.checkout_v1.DeleteFromCartRequest
+	4,  // 3: route256.checkout_v1.CheckoutService.ListCart:input_type -> route256.checkout_v1.ListCartRequest
+	7,  // 4: route256.checkout_v1.CheckoutService.Purchase:input_type -> route256.checkout_v1.PurchaseRequest
+	9,  // 5: route256.checkout_v1.CheckoutService.InvalidateProductsCache:input_type -> route256.checkout_v1.InvalidateProductsCacheRequest
+	1,  // 6: route256.checkout_v1.CheckoutService.AddToCart:output_type -> route256.checkout_v1.AddToCartResponse
+	3,  // 7: route256.checkout_v1.CheckoutService.DeleteFromCart:output_type -> route256.checkout_v1.DeleteFromCartResponse
+	6,  // 8: route256.checkout_v1.CheckoutService.ListCart:output_type -> route256.checkout_v1.ListCartResponse
+	8,  // 9: route256.checkout_v1.CheckoutService.Purchase:output_type -> route256.checkout_v1.PurchaseResponse
+	10, // 10: route256.checkout_v1.CheckoutService.InvalidateProductsCache:output_type -> route256.checkout_v1.InvalidateProductsCacheResponse
+	6,  // [6:11] is the sub-list for method output_type
+	1,  // [1:6] is the sub-list for method input_type
+	1,  // [1:1] is the sub-list for extension type_name
+	1,  // [1:1] is the sub-list for extension extendee
+	0,  // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_checkout_v1_service_proto_init() }
@@ -740,6 +856,30 @@ func file_checkout_v1_service_proto_init() {
 				return nil
 			}
 		}
+		file_checkout_v1_service_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*InvalidateProductsCacheRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_checkout_v1_service_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*InvalidateProductsCacheResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -747,7 +887,7 @@ func file_checkout_v1_service_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_checkout_v1_service_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   9,
+			NumMessages:   11,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -31,6 +31,8 @@ type CheckoutServiceClient interface {
 	ListCart(ctx context.Context, in *ListCartRequest, opts ...grpc.CallOption) (*ListCartResponse, error)
 	// Оформить заказ по всем товарам корзины
 	Purchase(ctx context.Context, in *PurchaseRequest, opts ...grpc.CallOption) (*PurchaseResponse, error)
+	// Сбросить кэш товаров на всех репликах checkout (административный метод)
+	InvalidateProductsCache(ctx context.Context, in *InvalidateProductsCacheRequest, opts ...grpc.CallOption) (*InvalidateProductsCacheResponse, error)
 }
 
 type checkoutServiceClient struct {
@@ -77,6 +79,15 @@ func (c *checkoutServiceClient) Purchase(ctx context.Context, in *PurchaseReques
 	return out, nil
 }
 
+func (c *checkoutServiceClient) InvalidateProductsCache(ctx context.Context, in *InvalidateProductsCacheRequest, opts ...grpc.CallOption) (*InvalidateProductsCacheResponse, error) {
+	out := new(InvalidateProductsCacheResponse)
+	err := c.cc.Invoke(ctx, "/route256.checkout_v1.CheckoutService/InvalidateProductsCache", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CheckoutServiceServer is the server API for CheckoutService service.
 // All implementations must embed UnimplementedCheckoutServiceServer
 // for forward compatibility
@@ -89,6 +100,8 @@ type CheckoutServiceServer interface {
 	ListCart(context.Context, *ListCartRequest) (*ListCartResponse, error)
 	// Оформить заказ по всем товарам корзины
 	Purchase(context.Context, *PurchaseRequest) (*PurchaseResponse, error)
+	// Сбросить кэш товаров на всех репликах checkout (административный метод)
+	InvalidateProductsCache(context.Context, *InvalidateProductsCacheRequest) (*InvalidateProductsCacheResponse, error)
 	mustEmbedUnimplementedCheckoutServiceServer()
 }
 
@@ -108,6 +121,9 @@ func (UnimplementedCheckoutServiceServer) ListCart(context.Context, *ListCartReq
 func (UnimplementedCheckoutServiceServer) Purchase(context.Context, *PurchaseRequest) (*PurchaseResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Purchase not implemented")
 }
+func (UnimplementedCheckoutServiceServer) InvalidateProductsCache(context.Context, *InvalidateProductsCacheRequest) (*InvalidateProductsCacheResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InvalidateProductsCache not implemented")
+}
 func (UnimplementedCheckoutServiceServer) mustEmbedUnimplementedCheckoutServiceServer() {}
 
 // UnsafeCheckoutServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -193,6 +209,24 @@ func _CheckoutService_Purchase_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CheckoutService_InvalidateProductsCache_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InvalidateProductsCacheRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckoutServiceServer).InvalidateProductsCache(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/route256.checkout_v1.CheckoutService/InvalidateProductsCache",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckoutServiceServer).InvalidateProductsCache(ctx, req.(*InvalidateProductsCacheRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // CheckoutService_ServiceDesc is the grpc.ServiceDesc for CheckoutService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -216,6 +250,10 @@ var CheckoutService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Purchase",
 			Handler:    _CheckoutService_Purchase_Handler,
 		},
+		{
+			MethodName: "InvalidateProductsCache",
+			Handler:    _CheckoutService_InvalidateProductsCache_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "checkout_v1_service.proto",
@@ -0,0 +1,155 @@
+// Bulk operations and iteration over cache
+// GetMany looks up all keys under one lock, it returns found values and the list of missing keys,
+// so caller can load missing records with one batch request
+// SetMany upserts records one by one with the same eviction rules as Set
+// Len, Keys and Range are intended for admin dumps and debugging:
+// Keys and Range work on copy of records made under lock, so callback can use cache without deadlock
+// Expired records and cached loader errors are treated as missing by GetMany, Keys and Range, but counted by Len until evicted
+
+package cache
+
+import "context"
+
+func (c *cache[KeyT, ValueT]) GetMany(ctx context.Context, keys []KeyT) (map[KeyT]ValueT, []KeyT) { // returns found values and missing keys
+	c.ConfigLock.RLock()
+	defer c.ConfigLock.RUnlock()
+
+	for _, key := range keys {
+		c.recordAccess(key)
+	}
+	found := make(map[KeyT]ValueT, len(keys))
+	var missing, refresh []KeyT
+	c.Lock.Lock()
+	for _, key := range keys {
+		node, ok := c.Storage[key]
+		if !ok || node.Err != nil {
+			missing = append(missing, key)
+			continue
+		}
+		if c.expired(node) {
+			if c.removable(node) {
+				c.removeNode(node, EvictTTL)
+			}
+			missing = append(missing, key)
+			continue
+		}
+		if c.needsRefresh(node) {
+			refresh = append(refresh, key)
+		}
+		c.updateLFU(node)
+		c.updateLRU(node)
+		found[key] = node.Value
+	}
+	c.unlockAndNotify()
+
+	c.stats.hits.Add(uint64(len(found)))
+	c.stats.misses.Add(uint64(len(missing)))
+	for _, key := range refresh {
+		c.refreshAsync(key)
+	}
+	return found, missing
+}
+
+func (c *cache[KeyT, ValueT]) SetMany(ctx context.Context, items map[KeyT]ValueT) int { // upserts values to cache, returns number of stored records
+	stored := 0
+	for key, value := range items {
+		if c.set(key, value, nil, 0) {
+			stored++
+		}
+	}
+	return stored
+}
+
+func (c *cache[KeyT, ValueT]) Len() int { // number of records in cache
+	c.Lock.RLock()
+	defer c.Lock.RUnlock()
+
+	return int(c.Size)
+}
+
+func (c *cache[KeyT, ValueT]) Keys() []KeyT { // keys of valid records
+	entries := c.valid()
+	keys := make([]KeyT, 0, len(entries))
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+	}
+	return keys
+}
+
+func (c *cache[KeyT, ValueT]) Range(fn func(key KeyT, value ValueT) bool) { // calls fn for valid records until it returns false
+	for _, entry := range c.valid() {
+		if !fn(entry.Key, entry.Value) {
+			return
+		}
+	}
+}
+
+func (c *cache[KeyT, ValueT]) valid() []snapshotEntry[KeyT, ValueT] { // copies records which can be returned by Get, order is not defined
+	c.ConfigLock.RLock()
+	defer c.ConfigLock.RUnlock()
+
+	c.Lock.RLock()
+	defer c.Lock.RUnlock()
+
+	entries := make([]snapshotEntry[KeyT, ValueT], 0, c.Size)
+	for _, node := range c.Storage {
+		if node.Err == nil && !c.expired(node) {
+			entries = append(entries, snapshotEntry[KeyT, ValueT]{Key: node.Key, Value: node.Value})
+		}
+	}
+	return entries
+}
+
+func (c *shardedCache[KeyT, ValueT]) GetMany(ctx context.Context, keys []KeyT) (map[KeyT]ValueT, []KeyT) { // looks up keys of every shard under its lock
+	shardKeys := make(map[*cache[KeyT, ValueT]][]KeyT)
+	for _, key := range keys {
+		shard := c.shard(key)
+		shardKeys[shard] = append(shardKeys[shard], key)
+	}
+	found := make(map[KeyT]ValueT, len(keys))
+	var missing []KeyT
+	for shard, keys := range shardKeys {
+		shardFound, shardMissing := shard.GetMany(ctx, keys)
+		for key, value := range shardFound {
+			found[key] = value
+		}
+		missing = append(missing, shardMissing...)
+	}
+	return found, missing
+}
+
+func (c *shardedCache[KeyT, ValueT]) SetMany(ctx context.Context, items map[KeyT]ValueT) int {
+	stored := 0
+	for key, value := range items {
+		if c.Set(ctx, key, value) {
+			stored++
+		}
+	}
+	return stored
+}
+
+func (c *shardedCache[KeyT, ValueT]) Len() int {
+	size := 0
+	for _, shard := range c.shards {
+		size += shard.Len()
+	}
+	return size
+}
+
+func (c *shardedCache[KeyT, ValueT]) Keys() []KeyT {
+	var keys []KeyT
+	for _, shard := range c.shards {
+		keys = append(keys, shard.Keys()...)
+	}
+	return keys
+}
+
+func (c *shardedCache[KeyT, ValueT]) Range(fn func(key KeyT, value ValueT) bool) {
+	for _, shard := range c.shards {
+		for _, entry := range shard.valid() {
+			if !fn(entry.Key, entry.Value) {
+				return
+			}
+		}
+	}
+}
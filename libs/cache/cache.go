@@ -1,8 +1,14 @@
 // Universal cache implementation using generics to define key and value types
 // Can be used with or without eviction
 // MaxSize configuration parameter = 0 means no upper size bound (eviction only by TTL if set)
-// Eviction can be based on LRU or LFU algorithms
-// Eviction on TTL in seconds can be used in combination with any eviction algorithm (TTL eviction triggered only on records addition)
+// MaxCost configuration parameter limits total cost of records computed by Cost function, it works together with MaxSize
+// Eviction can be based on LRU or LFU algorithms, LRU can be combined with scan resistant TinyLFU admission policy
+// Eviction on TTL in seconds can be used in combination with any eviction algorithm (TTL eviction triggered on records addition)
+// Optional background janitor evicts expired records every CleanupInterval seconds, it runs only while CleanupInterval > 0
+// and stops on ctx cancellation or Close call
+// Individual TTL can be set for record with SetWithTTL method, it overrides TTL from configuration for this record
+// Negative caching: loader errors from GetOrLoad selected by IsNegative (for example "not found") are cached for NegativeTTL seconds,
+// Get treats such records as missing
 // Cache configuration can be changed on the fly using SetConfiguration method
 // All data needed for different eviction methods is always computed and stored in Cache struct, so new configuration starts to work without any troubles
 //
@@ -13,15 +19,27 @@
 //   For LFU LFUCounter incremented on each key use and node position in LFU list is updated according to this counter
 //   For LRU node always moved to the head of LRU list on each key use
 //   For TTL UsedAt field updated to time.now() and node always moved to the head of TTL list only when Set method used
-// When MaxSize > 0 in Config and size of cache is >= MaxSize, then eviction started with TTL if it is set
+//   Records with individual TTL are kept in min-heap by expiration time instead of TTL list (see ttlheap.go)
+// When MaxSize > 0 in Config and size of cache is >= MaxSize (or new record doesn't fit in MaxCost), then eviction started with TTL if it is set
 // LFU or LRU eviction is applied if there is still no space after TTL eviction
 //
 // Two special methods can be used to invalidate discrete or all records in cache: Invalidate and Clear
+// OnEvict callback from configuration is called for every removed record with the reason of removal
+// Usage statistics are available with Stats method and can be exported to Prometheus (see collector.go)
+//
+// Sharded variant of cache (see sharded.go) splits keys between independent caches to reduce lock contention
+// Two-tier variant (see tiered.go) puts remote storage shared by service replicas behind in-process cache
+//
+// GetOrLoad method can be used for read-through caching, concurrent loads of the same key are coalesced (see loader.go)
+// GetMany and SetMany process many keys at once, Len, Keys and Range allow to inspect cache content (see bulk.go)
+// Cache records can be saved with Snapshot and loaded with Restore to warm up cache after restart (see snapshot.go)
+// Records close to expiration can be reloaded in background and stale records can be served on loader errors (see refresh.go)
 
 package cache
 
 import (
 	"context"
+	"io"
 	"sync"
 	"time"
 )
@@ -35,84 +53,162 @@ type Node[KeyT comparable, ValueT any] struct { // Node used in cache for value
 	LRUNext  *Node[KeyT, ValueT] // Next node in double linked list for LRU
 	LRUPrev  *Node[KeyT, ValueT] // Prev node in double linked list for LRU
 	UsedAt   time.Time           // Time of last node usage
+	TTL      uint64              // Individual time to live in seconds, 0 - TTL from configuration is used
+	TTLIndex int                 // Position in TTL heap for record with individual TTL
+	Err      error               // Cached loader error for negative caching, Value is empty if set
+	Cost     uint64              // Cost of record for MaxCost limit
 	TTLNext  *Node[KeyT, ValueT] // Next node in double linked list for TTL
 	TTLPrev  *Node[KeyT, ValueT] // Prev node in double linked list for TTL
 }
 
 type Cache[KeyT comparable, ValueT any] interface {
 	Set(ctx context.Context, key KeyT, value ValueT) bool
+	SetWithTTL(ctx context.Context, key KeyT, value ValueT, ttl uint64) bool
 	Get(ctx context.Context, key KeyT) (*ValueT, bool)
+	GetMany(ctx context.Context, keys []KeyT) (map[KeyT]ValueT, []KeyT)
+	SetMany(ctx context.Context, items map[KeyT]ValueT) int
+	GetOrLoad(ctx context.Context, key KeyT, loader Loader[KeyT, ValueT]) (*ValueT, error)
+	Load(ctx context.Context, key KeyT, loader Loader[KeyT, ValueT]) (*ValueT, error)
 	Invalidate(ctx context.Context, key KeyT) bool
 	SetConfig(ctx context.Context, config Config) error
+	SetLoader(loader Loader[KeyT, ValueT])
+	Snapshot(w io.Writer) error
+	Restore(r io.Reader) error
+	Len() int
+	Keys() []KeyT
+	Range(fn func(key KeyT, value ValueT) bool)
 	Clear(ctx context.Context) error
+	Close() error
+	Stats() Stats
 }
 
 type cache[KeyT comparable, ValueT any] struct {
-	Lock       sync.RWMutex
-	ConfigLock sync.RWMutex
-	Config     Config                       // cache configuration parameters
-	Size       uint64                       // Current cache size
-	Storage    map[KeyT]*Node[KeyT, ValueT] // Index map
-	LFUHead    *Node[KeyT, ValueT]          // Head of double linked list for LFU
-	LFUTail    *Node[KeyT, ValueT]          // Tail of double linked list for LFU
-	LRUHead    *Node[KeyT, ValueT]          // Head of double linked list for LRU
-	LRUTail    *Node[KeyT, ValueT]          // Tail of double linked list for LRU
-	TTLHead    *Node[KeyT, ValueT]          // Head of double linked list for TTL
-	TTLTail    *Node[KeyT, ValueT]          // Tail of double linked list for TTL
+	Lock         sync.RWMutex
+	ConfigLock   sync.RWMutex
+	Config       Config                       // cache configuration parameters
+	Size         uint64                       // Current cache size
+	CustomTTL    uint64                       // Number of records with individual TTL
+	TotalCost    uint64                       // Total cost of records
+	Storage      map[KeyT]*Node[KeyT, ValueT] // Index map
+	LFUHead      *Node[KeyT, ValueT]          // Head of double linked list for LFU
+	LFUTail      *Node[KeyT, ValueT]          // Tail of double linked list for LFU
+	LRUHead      *Node[KeyT, ValueT]          // Head of double linked list for LRU
+	LRUTail      *Node[KeyT, ValueT]          // Tail of double linked list for LRU
+	TTLHead      *Node[KeyT, ValueT]          // Head of double linked list for TTL
+	TTLTail      *Node[KeyT, ValueT]          // Tail of double linked list for TTL
+	TTLHeap      ttlHeap[KeyT, ValueT]        // Records with individual TTL ordered by expiration time
+	loads        loadGroup[KeyT, ValueT]      // In-flight loader calls for GetOrLoad
+	evicted      []eviction[KeyT, ValueT]     // Records removed under Lock, OnEvict is called for them after Lock release
+	stats        stats                        // Usage statistics
+	sketch       *sketch                      // Frequency sketch for TinyLFU admission, nil for other cache types
+	loader       Loader[KeyT, ValueT]         // Loader for refresh-ahead, protected by ConfigLock
+	ctx          context.Context              // Context for asynchronous refresh
+	reconfigured chan struct{}                // Signals janitor to reread configuration
+	janitorOn    bool                         // Janitor goroutine is running, protected by ConfigLock
+	closed       chan struct{}                // Closed by Close to stop janitor
+	closeOnce    sync.Once
 }
 
 const ( // cache types
-	Simple   = iota // No eviction
-	LFUCache        // LFU eviction
-	LRUCache        // LRU eviction
+	Simple       = iota // No eviction
+	LFUCache            // LFU eviction
+	LRUCache            // LRU eviction
+	TinyLFUCache        // LRU eviction with TinyLFU admission (see tinylfu.go)
 )
 
 type Config struct { // cache parameters
-	MaxSize uint64 // Maximum cache size, 0 - indefinite
-	Type    uint   // One of Simple (no eviction on growth), LFUCache (least frequently used eviction), LRU (least recently used eviction)
-	TTL     uint64 // Time to live for records in seconds, 0 - indefinite
+	MaxSize         uint64                                   // Maximum cache size, 0 - indefinite
+	Type            uint                                     // One of Simple (no eviction on growth), LFUCache (least frequently used eviction), LRU (least recently used eviction), TinyLFUCache (LRU eviction with frequency based admission)
+	TTL             uint64                                   // Time to live for records in seconds, 0 - indefinite
+	CleanupInterval uint64                                   // Interval in seconds for background eviction of expired records, 0 - no background eviction
+	NegativeTTL     uint64                                   // Time to live in seconds for loader errors cached by GetOrLoad, 0 - errors are not cached
+	IsNegative      func(err error) bool                     // Selects loader errors for negative caching, nil - errors are not cached, temporary errors must not be selected
+	MaxCost         uint64                                   // Maximum total cost of records, 0 - indefinite
+	Cost            func(key, value any) uint64              // Cost of record, for example its size in bytes, nil - every record costs 1
+	RefreshAhead    uint64                                   // Seconds before expiration when Get starts asynchronous reload with loader registered by SetLoader, 0 - no refresh
+	MaxStale        uint64                                   // Seconds after expiration when GetOrLoad returns stale record if loader fails, 0 - stale records are not returned
+	Codec           Codec                                    // Encoding of Snapshot, nil - gob
+	LoadTimeout     time.Duration                            // Timeout of loader calls made by GetOrLoad, 0 - 10s
+	OnEvict         func(key, value any, reason EvictReason) // Called for every removed or replaced record after cache lock is released, must not call methods of the same cache
+}
+
+type EvictReason uint // reason of record removal passed to OnEvict
+
+const ( // eviction reasons
+	EvictTTL        EvictReason = iota // Record expired
+	EvictLRU                           // Evicted by LRU algorithm
+	EvictLFU                           // Evicted by LFU algorithm
+	EvictInvalidate                    // Removed by Invalidate
+	EvictClear                         // Removed by Clear
+	EvictTinyLFU                       // Evicted by TinyLFU admission policy
+	EvictReplaced                      // Value replaced by Set or reload, OnEvict gets the old value
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case EvictTTL:
+		return "ttl"
+	case EvictLRU:
+		return "lru"
+	case EvictLFU:
+		return "lfu"
+	case EvictInvalidate:
+		return "invalidate"
+	case EvictClear:
+		return "clear"
+	case EvictTinyLFU:
+		return "tinylfu"
+	case EvictReplaced:
+		return "replaced"
+	default:
+		return "unknown"
+	}
+}
+
+type eviction[KeyT comparable, ValueT any] struct { // removed record waiting for OnEvict call
+	node   *Node[KeyT, ValueT]
+	reason EvictReason
 }
 
 func NewCache[KeyT comparable, ValueT any](ctx context.Context, config Config) (Cache[KeyT, ValueT], error) {
-	return &cache[KeyT, ValueT]{
-		Config:  config,
-		Storage: make(map[KeyT]*Node[KeyT, ValueT]),
-	}, nil
+	return newCache[KeyT, ValueT](ctx, config), nil
+}
+
+func newCache[KeyT comparable, ValueT any](ctx context.Context, config Config) *cache[KeyT, ValueT] {
+	c := &cache[KeyT, ValueT]{
+		Config:       config,
+		Storage:      make(map[KeyT]*Node[KeyT, ValueT]),
+		reconfigured: make(chan struct{}, 1),
+		closed:       make(chan struct{}),
+		sketch:       newSketchFor(config),
+		ctx:          ctx,
+	}
+	c.startJanitor()
+	return c
 }
 
 func (c *cache[KeyT, ValueT]) updateLFU(node *Node[KeyT, ValueT]) { // update node position in LFU double linked list towards LFUHead
 	node.LFUCount++
-	for node.LFUPrev != nil && node.LFUPrev.LFUCount < node.LFUCount {
+	for node.LFUPrev != nil && node.LFUPrev.LFUCount < node.LFUCount { // swap node with previous one
+		prev := node.LFUPrev
+		prev.LFUNext = node.LFUNext
 		if node.LFUNext == nil {
-			c.LFUTail = node.LFUPrev
+			c.LFUTail = prev
 		} else {
-			node.LFUNext.LFUPrev = node.LFUPrev
+			node.LFUNext.LFUPrev = prev
 		}
-		node.LFUNext = node.LFUPrev
-		node.LFUPrev = node.LFUPrev.LFUPrev
-		if node.LFUPrev == nil {
+		node.LFUPrev = prev.LFUPrev
+		node.LFUNext = prev
+		if prev.LFUPrev == nil {
 			c.LFUHead = node
-		}
-		node.LFUNext.LFUPrev = node
-	}
-}
-
-func (c *cache[KeyT, ValueT]) updateLRU(node *Node[KeyT, ValueT]) { // update node position in LRU double linked list, always to LRUHead
-	if node.LRUPrev != nil {
-		node.LRUPrev.LRUNext = node.LRUNext
-		if node.LRUNext == nil {
-			c.LRUTail = node.LRUPrev
 		} else {
-			node.LRUNext.LRUPrev = node.LRUPrev
+			prev.LFUPrev.LFUNext = node
 		}
-		node.LRUPrev = nil
-		node.LRUNext = c.LRUHead
-		c.LRUHead = node
+		prev.LFUPrev = node
 	}
 }
 
-func (c *cache[KeyT, ValueT]) updateTTL(node *Node[KeyT, ValueT]) { // update node position in TTL double linked list, always to TTLHead
-	node.UsedAt = time.Now()
+func (c *cache[KeyT, ValueT]) updateLRU(node *Node[KeyT, ValueT]) { // update node position in LRU double linked list, always to LRUHead
 	if node.LRUPrev != nil {
 		node.LRUPrev.LRUNext = node.LRUNext
 		if node.LRUNext == nil {
@@ -122,28 +218,60 @@ func (c *cache[KeyT, ValueT]) updateTTL(node *Node[KeyT, ValueT]) { // update no
 		}
 		node.LRUPrev = nil
 		node.LRUNext = c.LRUHead
+		c.LRUHead.LRUPrev = node
 		c.LRUHead = node
 	}
 }
 
 func (c *cache[KeyT, ValueT]) insertLFU(node *Node[KeyT, ValueT]) { // insert new node to LFU double linked list, always at LFUTail
 	node.LFUCount = 1
-	node.LFUPrev = nil
-	node.LFUNext = c.LFUHead
-	c.LFUHead = node
+	node.LFUNext = nil
+	node.LFUPrev = c.LFUTail
+	if c.LFUTail == nil {
+		c.LFUHead = node
+	} else {
+		c.LFUTail.LFUNext = node
+	}
+	c.LFUTail = node
 }
 
-func (c *cache[KeyT, ValueT]) insertLRU(node *Node[KeyT, ValueT]) { // insert new node to LFU double linked list, always at LRUHead
+func (c *cache[KeyT, ValueT]) insertLRU(node *Node[KeyT, ValueT]) { // insert new node to LRU double linked list, always at LRUHead
 	node.LRUPrev = nil
 	node.LRUNext = c.LRUHead
+	if c.LRUHead == nil {
+		c.LRUTail = node
+	} else {
+		c.LRUHead.LRUPrev = node
+	}
 	c.LRUHead = node
 }
 
-func (c *cache[KeyT, ValueT]) insertTTL(node *Node[KeyT, ValueT]) { // insert new node to LFU double linked list, always at TTLHead
-	node.UsedAt = time.Now()
-	node.TTLPrev = nil
-	node.TTLNext = c.LFUHead
-	c.LFUHead = node
+func (c *cache[KeyT, ValueT]) insertTTL(node *Node[KeyT, ValueT], usedAt time.Time) { // insert node set at usedAt (zero - now) to TTL double linked list ordered by UsedAt, at TTLHead unless it is restored, or to TTL heap if it has individual TTL
+	if usedAt.IsZero() {
+		usedAt = time.Now()
+	}
+	node.UsedAt = usedAt
+	if node.TTL > 0 {
+		c.pushTTL(node)
+		return
+	}
+	next := c.TTLHead
+	for next != nil && next.UsedAt.After(usedAt) {
+		next = next.TTLNext
+	}
+	node.TTLNext = next
+	if next == nil {
+		node.TTLPrev = c.TTLTail
+		c.TTLTail = node
+	} else {
+		node.TTLPrev = next.TTLPrev
+		next.TTLPrev = node
+	}
+	if node.TTLPrev == nil {
+		c.TTLHead = node
+	} else {
+		node.TTLPrev.TTLNext = node
+	}
 }
 
 func (c *cache[KeyT, ValueT]) removeLFU(node *Node[KeyT, ValueT]) { // remove node from LFU double linked list
@@ -177,8 +305,12 @@ func (c *cache[KeyT, ValueT]) removeLRU(node *Node[KeyT, ValueT]) { // remove no
 	node.LRUNext = nil
 }
 
-func (c *cache[KeyT, ValueT]) removeTTL(node *Node[KeyT, ValueT]) { // remove node from TTL double linked list
+func (c *cache[KeyT, ValueT]) removeTTL(node *Node[KeyT, ValueT]) { // remove node from TTL double linked list or TTL heap
 	node.UsedAt = time.Time{}
+	if node.TTL > 0 {
+		c.removeHeapTTL(node)
+		return
+	}
 	if node.TTLNext == nil {
 		c.TTLTail = node.TTLPrev
 	} else {
@@ -193,33 +325,99 @@ func (c *cache[KeyT, ValueT]) removeTTL(node *Node[KeyT, ValueT]) { // remove no
 	node.TTLNext = nil
 }
 
-func (c *cache[KeyT, ValueT]) updateNode(node *Node[KeyT, ValueT], value ValueT) {
-	c.Lock.Lock()
-	c.Storage[node.Key].Value = value
-	c.updateLFU(node)
-	c.updateLRU(node)
-	c.updateTTL(node)
-	c.Lock.Unlock()
+func (c *cache[KeyT, ValueT]) setNodeData(node *Node[KeyT, ValueT], value ValueT, err error, ttl uint64, cost uint64) { // sets node value, cached error, individual TTL and cost
+	if node.TTL > 0 {
+		c.CustomTTL--
+	}
+	if ttl > 0 {
+		c.CustomTTL++
+	}
+	c.TotalCost = c.TotalCost - node.Cost + cost
+	node.Value = value
+	node.Err = err
+	node.TTL = ttl
+	node.Cost = cost
 }
 
-func (c *cache[KeyT, ValueT]) refreshNode(node *Node[KeyT, ValueT]) {
+func (c *cache[KeyT, ValueT]) refreshNode(node *Node[KeyT, ValueT]) (*ValueT, bool, error) { // returns copy of node value and cached error, false if node was removed by concurrent call
 	c.Lock.Lock()
+	defer c.Lock.Unlock()
+
+	if c.Storage[node.Key] != node {
+		return nil, false, nil
+	}
 	c.updateLFU(node)
 	c.updateLRU(node)
-	c.Lock.Unlock()
+	if node.Err != nil {
+		return nil, true, node.Err
+	}
+	value := node.Value
+	return &value, true, nil
 }
 
-func (c *cache[KeyT, ValueT]) insertNode(node *Node[KeyT, ValueT]) {
+func (c *cache[KeyT, ValueT]) upsertNode(key KeyT, value ValueT, err error, ttl uint64, cost uint64, usedAt time.Time) bool { // updates or inserts node, returns false if there is no room for it
 	c.Lock.Lock()
-	c.Storage[node.Key] = node
+	defer c.unlockAndNotify()
+
+	node, exists := c.Storage[key]
+	if exists && !usedAt.IsZero() { // restored record is older than record set concurrently with Restore, it is skipped
+		return true
+	}
+	if c.noRoom(key, cost) {
+		return false
+	}
+	if exists {
+		c.replaceNode(node)
+		c.removeTTL(node) // individual TTL may change, so node is reinserted to TTL list or heap
+		c.setNodeData(node, value, err, ttl, cost)
+		c.updateLFU(node)
+		c.updateLRU(node)
+		c.insertTTL(node, usedAt)
+		return true
+	}
+	node = &Node[KeyT, ValueT]{Key: key}
+	c.setNodeData(node, value, err, ttl, cost)
+	c.Storage[key] = node
 	c.insertLFU(node)
 	c.insertLRU(node)
-	c.insertTTL(node)
+	c.insertTTL(node, usedAt)
 	c.Size++
-	c.Lock.Unlock()
+	c.stats.inserts.Add(1)
+	return true
+}
+
+func (c *cache[KeyT, ValueT]) noRoom(key KeyT, cost uint64) bool { // checks if record with cost can't be upserted without eviction
+	if node, ok := c.Storage[key]; ok { // update doesn't change size, only cost
+		return c.Config.MaxCost > 0 && c.TotalCost-node.Cost+cost > c.Config.MaxCost
+	}
+	return c.Config.MaxSize > 0 && c.Size >= c.Config.MaxSize ||
+		c.Config.MaxCost > 0 && c.TotalCost+cost > c.Config.MaxCost
+}
+
+func (c *cache[KeyT, ValueT]) cost(key KeyT, value ValueT) uint64 { // cost of record, 1 if cost function is not set
+	if c.Config.Cost == nil {
+		return 1
+	}
+	return c.Config.Cost(key, value)
 }
 
-func (c *cache[KeyT, ValueT]) removeNode(node *Node[KeyT, ValueT]) {
+func (c *cache[KeyT, ValueT]) replaceNode(node *Node[KeyT, ValueT]) { // reports old value of node which value is about to be replaced
+	c.stats.evictions[EvictReplaced].Add(1)
+	if c.Config.OnEvict != nil {
+		old := &Node[KeyT, ValueT]{Key: node.Key, Value: node.Value}
+		c.evicted = append(c.evicted, eviction[KeyT, ValueT]{node: old, reason: EvictReplaced})
+	}
+}
+
+func (c *cache[KeyT, ValueT]) removeNode(node *Node[KeyT, ValueT], reason EvictReason) {
+	c.stats.evictions[reason].Add(1)
+	if c.Config.OnEvict != nil {
+		c.evicted = append(c.evicted, eviction[KeyT, ValueT]{node: node, reason: reason})
+	}
+	if node.TTL > 0 {
+		c.CustomTTL--
+	}
+	c.TotalCost -= node.Cost
 	delete(c.Storage, node.Key)
 	c.removeLFU(node)
 	c.removeLRU(node)
@@ -227,111 +425,201 @@ func (c *cache[KeyT, ValueT]) removeNode(node *Node[KeyT, ValueT]) {
 	c.Size--
 }
 
+func (c *cache[KeyT, ValueT]) ttl(node *Node[KeyT, ValueT]) uint64 { // node TTL, individual TTL has priority over TTL from configuration
+	if node.TTL > 0 {
+		return node.TTL
+	}
+	return c.Config.TTL
+}
+
+func (c *cache[KeyT, ValueT]) age(node *Node[KeyT, ValueT]) uint64 { // seconds since node was set
+	return uint64(time.Since(node.UsedAt).Seconds())
+}
+
+func (c *cache[KeyT, ValueT]) expired(node *Node[KeyT, ValueT]) bool { // checks if node can't be returned by Get
+	ttl := c.ttl(node)
+	return ttl > 0 && c.age(node) >= ttl
+}
+
+func (c *cache[KeyT, ValueT]) removable(node *Node[KeyT, ValueT]) bool { // checks if node is expired and can't be returned as stale value too
+	ttl := c.ttl(node)
+	return ttl > 0 && c.age(node) >= ttl+c.Config.MaxStale
+}
+
+func (c *cache[KeyT, ValueT]) hasTTL() bool { // checks if any record can expire
+	return c.Config.TTL > 0 || c.CustomTTL > 0
+}
+
 func (c *cache[KeyT, ValueT]) evictByTTL() {
 	c.Lock.Lock()
-	for c.TTLTail != nil && uint64(time.Since(c.TTLTail.UsedAt).Seconds()) >= c.Config.TTL {
-		c.removeNode(c.TTLTail)
+	for c.TTLTail != nil && c.removable(c.TTLTail) { // TTL list is sorted by expiration time
+		c.removeNode(c.TTLTail, EvictTTL)
 	}
-	c.Lock.Unlock()
+	for len(c.TTLHeap) > 0 && c.removable(c.TTLHeap[0]) {
+		c.removeNode(c.TTLHeap[0], EvictTTL)
+	}
+	c.unlockAndNotify()
 }
 
-func (c *cache[KeyT, ValueT]) evictByLFU() {
+func (c *cache[KeyT, ValueT]) evictExpired(node *Node[KeyT, ValueT]) { // removes expired node if it was not updated by concurrent call
 	c.Lock.Lock()
-	for c.LFUTail != nil && c.Size >= c.Config.MaxSize {
-		c.removeNode(c.LFUTail)
+	if c.Storage[node.Key] == node && c.removable(node) {
+		c.removeNode(node, EvictTTL)
 	}
-	c.Lock.Unlock()
+	c.unlockAndNotify()
 }
 
-func (c *cache[KeyT, ValueT]) evictByLRU() {
+func (c *cache[KeyT, ValueT]) evictByLFU(key KeyT, cost uint64) { // evicts records until there is room for key with cost
 	c.Lock.Lock()
-	for c.LRUTail != nil && c.Size >= c.Config.MaxSize {
-		c.removeNode(c.LRUTail)
+	for c.LFUTail != nil && c.noRoom(key, cost) {
+		c.removeNode(c.LFUTail, EvictLFU)
 	}
+	c.unlockAndNotify()
+}
+
+func (c *cache[KeyT, ValueT]) evictByLRU(key KeyT, cost uint64) { // evicts records until there is room for key with cost
+	c.Lock.Lock()
+	for c.LRUTail != nil && c.noRoom(key, cost) {
+		c.removeNode(c.LRUTail, EvictLRU)
+	}
+	c.unlockAndNotify()
+}
+
+func (c *cache[KeyT, ValueT]) unlockAndNotify() { // releases Lock and calls OnEvict for records removed while Lock was held
+	evicted := c.evicted
+	c.evicted = nil
 	c.Lock.Unlock()
+	for _, e := range evicted {
+		c.Config.OnEvict(e.node.Key, e.node.Value, e.reason)
+	}
 }
 
 func (c *cache[KeyT, ValueT]) Set(ctx context.Context, key KeyT, value ValueT) bool { // upsert value to cache, returns false if cache is full
+	return c.set(key, value, nil, 0)
+}
+
+func (c *cache[KeyT, ValueT]) SetWithTTL(ctx context.Context, key KeyT, value ValueT, ttl uint64) bool { // upsert value to cache with individual TTL in seconds, returns false if cache is full
+	return c.set(key, value, nil, ttl)
+}
+
+func (c *cache[KeyT, ValueT]) set(key KeyT, value ValueT, err error, ttl uint64) bool {
+	return c.setAt(key, value, err, ttl, time.Time{})
+}
+
+func (c *cache[KeyT, ValueT]) setAt(key KeyT, value ValueT, err error, ttl uint64, usedAt time.Time) bool { // set with time of last Set, zero usedAt - now
 	c.ConfigLock.RLock()
 	defer c.ConfigLock.RUnlock()
 
-	c.Lock.RLock()
-	if node, ok := c.Storage[key]; ok { // update value in cache
-		c.Lock.RUnlock()
-		c.updateNode(node, value)
-		return true
+	cost := c.cost(key, value)
+	if c.Config.MaxCost > 0 && cost > c.Config.MaxCost { // record will never fit in cache
+		c.stats.rejected.Add(1)
+		return false
 	}
-	if c.Config.Type == Simple && c.Config.TTL > 0 { // evict only by TTL
-		c.Lock.RUnlock()
+
+	c.Lock.RLock()
+	_, exists := c.Storage[key]
+	noRoom := c.noRoom(key, cost)
+	c.Lock.RUnlock()
+
+	if !exists && c.Config.Type == Simple && c.Config.TTL > 0 || noRoom && c.hasTTL() { // evict expired records first
 		c.evictByTTL()
-	} else if c.Config.MaxSize > 0 && c.Size >= c.Config.MaxSize { // need to evict some records to insert new one
-		if c.Config.TTL > 0 {
-			c.Lock.RUnlock()
-			c.evictByTTL()
-			c.Lock.RLock()
-		}
-		if c.Config.Type != Simple && c.Size >= c.Config.MaxSize {
-			c.Lock.RUnlock()
-			switch c.Config.Type {
-			case LFUCache:
-				c.evictByLFU()
-			case LRUCache:
-				c.evictByLRU()
-			}
-			c.Lock.RLock()
-		}
 	}
-	if c.Config.MaxSize == 0 || c.Size < c.Config.MaxSize { // insert new record if not full
-		node := Node[KeyT, ValueT]{
-			Key:   key,
-			Value: value,
+	if noRoom { // need to evict some records to upsert new one
+		switch c.Config.Type {
+		case LFUCache:
+			c.evictByLFU(key, cost)
+		case LRUCache:
+			c.evictByLRU(key, cost)
+		case TinyLFUCache:
+			c.evictByTinyLFU(key, cost)
 		}
-		c.Lock.RUnlock()
-		c.insertNode(&node)
+	}
+	if c.upsertNode(key, value, err, ttl, cost, usedAt) {
 		return true
 	}
-	c.Lock.RUnlock()
+	c.stats.rejected.Add(1)
 	return false // cache is full and no records can be evicted
 }
 
 func (c *cache[KeyT, ValueT]) Get(ctx context.Context, key KeyT) (*ValueT, bool) { // return value from cache, if not in cache returns false
+	value, ok, err := c.lookup(key)
+	if !ok || err != nil {
+		c.stats.lookup(false)
+		return nil, false
+	}
+	c.stats.lookup(true)
+	return value, true
+}
+
+func (c *cache[KeyT, ValueT]) lookup(key KeyT) (*ValueT, bool, error) { // get with access counting for TinyLFU
+	c.ConfigLock.RLock()
+	c.recordAccess(key)
+	c.ConfigLock.RUnlock()
+	return c.get(key)
+}
+
+func (c *cache[KeyT, ValueT]) get(key KeyT) (*ValueT, bool, error) { // return value or cached loader error from cache, if not in cache returns false
 	c.ConfigLock.RLock()
 	defer c.ConfigLock.RUnlock()
 
 	c.Lock.RLock()
 	if node, ok := c.Storage[key]; ok {
-		if c.Config.TTL > 0 && uint64(time.Since(node.UsedAt).Seconds()) >= c.Config.TTL {
+		if c.expired(node) {
 			c.Lock.RUnlock()
-			c.evictByTTL()
-			return nil, false
+			c.evictExpired(node)
+			return nil, false, nil
 		}
+		refresh := c.needsRefresh(node)
 		c.Lock.RUnlock()
-		c.refreshNode(node)
-		return &node.Value, true
+		value, ok, err := c.refreshNode(node)
+		if refresh && ok && err == nil {
+			c.refreshAsync(key)
+		}
+		return value, ok, err
 	}
 	c.Lock.RUnlock()
-	return nil, false
+	return nil, false, nil
+}
+
+func (c *cache[KeyT, ValueT]) setError(key KeyT, err error) bool { // caches loader error if negative caching is configured, returns true if error is cached
+	c.ConfigLock.RLock()
+	ttl := c.Config.NegativeTTL
+	isNegative := c.Config.IsNegative
+	c.ConfigLock.RUnlock()
+
+	if ttl == 0 || isNegative == nil || !isNegative(err) {
+		return false
+	}
+	var empty ValueT
+	return c.set(key, empty, err, ttl)
 }
 
 func (c *cache[KeyT, ValueT]) Invalidate(ctx context.Context, key KeyT) bool { // removes record from cache, if not in cache returns false
 	c.ConfigLock.RLock()
 	defer c.ConfigLock.RUnlock()
 
-	c.Lock.RLock()
-	if node, ok := c.Storage[key]; ok {
-		c.Lock.RUnlock()
-		c.removeNode(node)
-		return true
+	c.Lock.Lock()
+	node, ok := c.Storage[key]
+	if ok {
+		c.removeNode(node, EvictInvalidate)
 	}
-	c.Lock.RUnlock()
-	return false
+	c.unlockAndNotify()
+	return ok
 }
 
 func (c *cache[KeyT, ValueT]) SetConfig(ctx context.Context, config Config) error { // sets new configuration for cache at any time
 	c.ConfigLock.Lock()
 	defer c.ConfigLock.Unlock()
 
+	if config.Type != c.Config.Type || config.MaxSize != c.Config.MaxSize {
+		c.sketch = newSketchFor(config)
+	}
 	c.Config = config
+	c.startJanitor()
+	select { // wake up janitor to apply new cleanup interval
+	case c.reconfigured <- struct{}{}:
+	default:
+	}
 	return nil
 }
 
@@ -340,15 +628,74 @@ func (c *cache[KeyT, ValueT]) Clear(ctx context.Context) error { // Clear cache,
 	defer c.ConfigLock.Unlock()
 
 	c.Lock.Lock()
-	defer c.Lock.Unlock()
+	if c.Config.OnEvict != nil {
+		for _, node := range c.Storage {
+			c.evicted = append(c.evicted, eviction[KeyT, ValueT]{node: node, reason: EvictClear})
+		}
+	}
+	defer c.unlockAndNotify()
 
+	c.stats.evictions[EvictClear].Add(c.Size)
 	c.Storage = make(map[KeyT]*Node[KeyT, ValueT])
 	c.Size = 0
+	c.CustomTTL = 0
+	c.TotalCost = 0
+	c.sketch = newSketchFor(c.Config)
 	c.LFUHead = nil
 	c.LFUTail = nil
 	c.LRUHead = nil
 	c.LRUTail = nil
 	c.TTLHead = nil
 	c.TTLTail = nil
+	c.TTLHeap = nil
 	return nil
 }
+
+func (c *cache[KeyT, ValueT]) Close() error { // stops background janitor, cache can still be used after Close
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	return nil
+}
+
+func (c *cache[KeyT, ValueT]) startJanitor() { // starts janitor if CleanupInterval is set and it isn't running, must be called under ConfigLock
+	if c.Config.CleanupInterval == 0 || c.janitorOn {
+		return
+	}
+	select {
+	case <-c.closed:
+		return
+	default:
+	}
+	c.janitorOn = true
+	go c.janitor(c.ctx)
+}
+
+func (c *cache[KeyT, ValueT]) janitor(ctx context.Context) { // evicts expired records every CleanupInterval seconds until ctx is done, cache is closed or CleanupInterval is reset to 0
+	for {
+		c.ConfigLock.Lock()
+		interval := c.Config.CleanupInterval
+		if interval == 0 {
+			c.janitorOn = false
+			c.ConfigLock.Unlock()
+			return
+		}
+		c.ConfigLock.Unlock()
+
+		timer := time.NewTimer(time.Duration(interval) * time.Second)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-c.closed:
+			timer.Stop()
+			return
+		case <-c.reconfigured:
+			timer.Stop()
+		case <-timer.C:
+			c.ConfigLock.RLock()
+			c.evictByTTL()
+			c.ConfigLock.RUnlock()
+		}
+	}
+}
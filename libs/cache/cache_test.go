@@ -0,0 +1,936 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"route256/libs/redis"
+	"route256/libs/redis/redistest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEviction(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		config  Config
+		use     []int
+		evicted int
+	}{
+		{
+			name:    "lru evicts least recently used",
+			config:  Config{MaxSize: 3, Type: LRUCache},
+			use:     []int{1, 2},
+			evicted: 3,
+		},
+		{
+			name:    "lfu evicts least frequently used",
+			config:  Config{MaxSize: 3, Type: LFUCache},
+			use:     []int{1, 1, 2, 3, 3},
+			evicted: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCache[int, int](ctx, tt.config)
+			require.NoError(t, err)
+			defer c.Close()
+
+			for i := 1; i <= 3; i++ {
+				require.True(t, c.Set(ctx, i, i))
+			}
+			for _, key := range tt.use {
+				_, ok := c.Get(ctx, key)
+				require.True(t, ok)
+			}
+			require.True(t, c.Set(ctx, 4, 4))
+
+			_, ok := c.Get(ctx, tt.evicted)
+			require.False(t, ok)
+			value, ok := c.Get(ctx, 4)
+			require.True(t, ok)
+			require.Equal(t, 4, *value)
+		})
+	}
+}
+
+func TestScanResistance(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		cacheT   uint
+		wantHits int
+	}{
+		{name: "lru", cacheT: LRUCache, wantHits: 0},
+		{name: "tinylfu", cacheT: TinyLFUCache, wantHits: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCache[int, int](ctx, Config{MaxSize: 10, Type: tt.cacheT})
+			require.NoError(t, err)
+			defer c.Close()
+
+			for i := 0; i < 10; i++ {
+				c.Set(ctx, i, i)
+				for j := 0; j < 10; j++ {
+					c.Get(ctx, i)
+				}
+			}
+			for i := 100; i < 150; i++ { // scan of unique keys
+				if _, ok := c.Get(ctx, i); !ok {
+					c.Set(ctx, i, i)
+				}
+			}
+			hits := 0
+			for i := 0; i < 10; i++ {
+				if _, ok := c.Get(ctx, i); ok {
+					hits++
+				}
+			}
+			require.Equal(t, tt.wantHits, hits)
+		})
+	}
+}
+
+func TestScanResistanceGetOrLoad(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewCache[int, int](ctx, Config{MaxSize: 10, Type: TinyLFUCache})
+	require.NoError(t, err)
+	defer c.Close()
+
+	loader := func(_ context.Context, key int) (int, error) {
+		return key, nil
+	}
+	for i := 0; i < 10; i++ { // warm keys, the first lookup is a miss
+		for j := 0; j < 5; j++ {
+			_, err := c.GetOrLoad(ctx, i, loader)
+			require.NoError(t, err)
+		}
+	}
+	require.Equal(t, uint8(5), c.(*cache[int, int]).sketch.estimate(sketchHash(0)), "access is counted once per lookup")
+	for i := 100; i < 150; i++ { // scan of unique keys
+		_, err := c.GetOrLoad(ctx, i, loader)
+		require.NoError(t, err)
+	}
+	for i := 0; i < 10; i++ {
+		_, ok := c.Get(ctx, i)
+		require.True(t, ok, "warm key %v is evicted by scan", i)
+	}
+}
+
+func TestTinyLFUAdmitsFrequentKeys(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewCache[int, int](ctx, Config{MaxSize: 2, Type: TinyLFUCache})
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.True(t, c.Set(ctx, 1, 1))
+	require.True(t, c.Set(ctx, 2, 2))
+	require.False(t, c.Set(ctx, 3, 3))
+	for i := 0; i < 5; i++ {
+		c.Get(ctx, 3)
+	}
+	require.True(t, c.Set(ctx, 3, 3))
+	require.NoError(t, c.SetConfig(ctx, Config{MaxSize: 2, Type: LRUCache}))
+	require.Nil(t, c.(*cache[int, int]).sketch)
+	require.True(t, c.Set(ctx, 4, 4))
+}
+
+func TestOnEvict(t *testing.T) {
+	ctx := context.Background()
+	var evicted []string
+	c, err := NewCache[int, int](ctx, Config{
+		MaxSize: 2,
+		Type:    LRUCache,
+		OnEvict: func(key, value any, reason EvictReason) {
+			evicted = append(evicted, fmt.Sprintf("%v=%v:%v", key, value, reason))
+		},
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.True(t, c.Set(ctx, 1, 10))
+	require.True(t, c.Set(ctx, 2, 20))
+	require.True(t, c.Set(ctx, 3, 30))
+	require.True(t, c.SetWithTTL(ctx, 4, 40, 1))
+	require.True(t, c.Invalidate(ctx, 3))
+	time.Sleep(1100 * time.Millisecond)
+	_, ok := c.Get(ctx, 4)
+	require.False(t, ok)
+	require.True(t, c.Set(ctx, 5, 50))
+	require.True(t, c.Set(ctx, 5, 51))
+	require.NoError(t, c.Clear(ctx))
+
+	require.Equal(t, []string{"1=10:lru", "2=20:lru", "3=30:invalidate", "4=40:ttl", "5=50:replaced", "5=51:clear"}, evicted)
+	require.Equal(t, uint64(1), c.Stats().Evictions[EvictReplaced])
+}
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewShardedCache[int, int](ctx, Config{MaxSize: 4, Type: LRUCache}, 2, nil)
+	require.NoError(t, err)
+	defer c.Close()
+
+	for i := 0; i < 10; i++ {
+		c.Set(ctx, i, i)
+	}
+	for i := 0; i < 10; i++ {
+		c.Get(ctx, i)
+	}
+	c.Invalidate(ctx, 9)
+
+	stats := c.Stats()
+	require.Equal(t, uint64(10), stats.Inserts)
+	require.Equal(t, uint64(10), stats.Hits+stats.Misses)
+	require.Equal(t, stats.Inserts-stats.Evictions[EvictLRU]-stats.Evictions[EvictInvalidate], stats.Size)
+	require.Equal(t, uint64(1), stats.Evictions[EvictInvalidate])
+	require.Equal(t, stats.Hits-1, stats.Size)
+}
+
+func TestMaxCost(t *testing.T) {
+	ctx := context.Background()
+	cost := func(key, value any) uint64 {
+		return uint64(len(value.(string)))
+	}
+
+	tests := []struct {
+		name     string
+		config   Config
+		set      []string
+		rejected []bool
+		want     []int
+	}{
+		{
+			name:     "lru evicts until cost fits",
+			config:   Config{Type: LRUCache, MaxCost: 10, Cost: cost},
+			set:      []string{"aaaa", "bbbb", "cccccc", "ddddddddddd"},
+			rejected: []bool{false, false, false, true},
+			want:     []int{1, 2},
+		},
+		{
+			name:     "simple rejects records over cost",
+			config:   Config{Type: Simple, MaxCost: 10, Cost: cost},
+			set:      []string{"aaaa", "bbbb", "cccccc", "dd"},
+			rejected: []bool{false, false, true, false},
+			want:     []int{0, 1, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCache[int, string](ctx, tt.config)
+			require.NoError(t, err)
+			defer c.Close()
+
+			for i, value := range tt.set {
+				require.Equal(t, !tt.rejected[i], c.Set(ctx, i, value))
+			}
+			var got []int
+			for i := range tt.set {
+				if _, ok := c.Get(ctx, i); ok {
+					got = append(got, i)
+				}
+			}
+			require.Equal(t, tt.want, got)
+			require.LessOrEqual(t, c.Stats().Cost, tt.config.MaxCost)
+		})
+	}
+}
+
+func TestSimpleCacheFull(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewCache[int, int](ctx, Config{MaxSize: 2, Type: Simple})
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.True(t, c.Set(ctx, 1, 1))
+	require.True(t, c.Set(ctx, 2, 2))
+	require.False(t, c.Set(ctx, 3, 3))
+	require.True(t, c.Set(ctx, 2, 20))
+
+	value, ok := c.Get(ctx, 2)
+	require.True(t, ok)
+	require.Equal(t, 20, *value)
+}
+
+func TestGetOrLoadFirstCallerCancelled(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewCache[int, string](ctx, Config{})
+	require.NoError(t, err)
+	defer c.Close()
+
+	started := make(chan struct{})
+	loader := func(ctx context.Context, key int) (string, error) {
+		close(started)
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+			return strconv.Itoa(key), nil
+		}
+	}
+	firstCtx, cancel := context.WithCancel(ctx)
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(firstCtx, 42, loader)
+		first <- err
+	}()
+	<-started
+	cancel()
+
+	value, err := c.GetOrLoad(ctx, 42, loader) // waits for loader started by the first caller
+	require.NoError(t, err)
+	require.Equal(t, "42", *value)
+	require.NoError(t, <-first)
+}
+
+func TestGetOrLoadTimeout(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewCache[int, string](ctx, Config{LoadTimeout: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer c.Close()
+
+	_, err = c.GetOrLoad(ctx, 1, func(ctx context.Context, key int) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestGetOrLoadPanic(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewCache[int, string](ctx, Config{})
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.Panics(t, func() {
+		_, _ = c.GetOrLoad(ctx, 1, func(ctx context.Context, key int) (string, error) {
+			panic("loader failed")
+		})
+	})
+	value, err := c.GetOrLoad(ctx, 1, func(ctx context.Context, key int) (string, error) { // doesn't wait for panicked call
+		return "1", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "1", *value)
+}
+
+func TestJanitor(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewCache[int, int](ctx, Config{TTL: 1, CleanupInterval: 1})
+	require.NoError(t, err)
+	defer c.Close()
+
+	for i := 0; i < 10; i++ {
+		require.True(t, c.Set(ctx, i, i))
+	}
+	require.Eventually(t, func() bool {
+		inner := c.(*cache[int, int])
+		inner.Lock.RLock()
+		defer inner.Lock.RUnlock()
+		return len(inner.Storage) == 0 && inner.Size == 0
+	}, 5*time.Second, 100*time.Millisecond)
+}
+
+func TestJanitorStartsOnlyWithCleanupInterval(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewCache[int, int](ctx, Config{TTL: 1})
+	require.NoError(t, err)
+	defer c.Close()
+	inner := c.(*cache[int, int])
+	janitorOn := func() bool {
+		inner.ConfigLock.RLock()
+		defer inner.ConfigLock.RUnlock()
+		return inner.janitorOn
+	}
+	require.False(t, janitorOn())
+
+	require.True(t, c.Set(ctx, 1, 1))
+	require.NoError(t, c.SetConfig(ctx, Config{TTL: 1, CleanupInterval: 1}))
+	require.True(t, janitorOn())
+	require.Eventually(t, func() bool {
+		inner.Lock.RLock()
+		defer inner.Lock.RUnlock()
+		return inner.Size == 0
+	}, 5*time.Second, 100*time.Millisecond)
+
+	require.NoError(t, c.SetConfig(ctx, Config{TTL: 1}))
+	require.Eventually(t, func() bool { return !janitorOn() }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, c.Close())
+	require.NoError(t, c.SetConfig(ctx, Config{TTL: 1, CleanupInterval: 1}))
+	require.False(t, janitorOn())
+}
+
+func TestGetOrLoadCoalescing(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewCache[int, string](ctx, Config{})
+	require.NoError(t, err)
+	defer c.Close()
+
+	var calls int32
+	loader := func(ctx context.Context, key int) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		return strconv.Itoa(key), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := c.GetOrLoad(ctx, 42, loader)
+			require.NoError(t, err)
+			require.Equal(t, "42", *value)
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestLoadAfterMiss(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewCache[int, string](ctx, Config{})
+	require.NoError(t, err)
+	defer c.Close()
+
+	loader := func(ctx context.Context, key int) (string, error) {
+		return strconv.Itoa(key), nil
+	}
+	_, ok := c.Get(ctx, 1)
+	require.False(t, ok)
+	value, err := c.Load(ctx, 1, loader)
+	require.NoError(t, err)
+	require.Equal(t, "1", *value)
+	value, err = c.Load(ctx, 1, func(ctx context.Context, key int) (string, error) { // loaded by previous call
+		return "", errors.New("unexpected loader call")
+	})
+	require.NoError(t, err)
+	require.Equal(t, "1", *value)
+
+	stats := c.Stats()
+	require.Equal(t, uint64(1), stats.Misses)
+	require.Equal(t, uint64(0), stats.Hits)
+}
+
+func TestSetWithTTL(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewCache[int, int](ctx, Config{TTL: 60})
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.True(t, c.SetWithTTL(ctx, 1, 1, 1))
+	require.True(t, c.Set(ctx, 2, 2))
+	time.Sleep(1100 * time.Millisecond)
+
+	_, ok := c.Get(ctx, 1)
+	require.False(t, ok)
+	value, ok := c.Get(ctx, 2)
+	require.True(t, ok)
+	require.Equal(t, 2, *value)
+}
+
+func TestRestoreKeepsTTLOrder(t *testing.T) {
+	ctx := context.Background()
+	c := newCache[int, int](ctx, Config{TTL: 2})
+	defer c.Close()
+
+	now := time.Now()
+	var buf bytes.Buffer
+	require.NoError(t, writeSnapshot(&buf, GobCodec{}, []snapshotEntry[int, int]{
+		{Key: 1, Value: 1, UsedAt: now.Add(-1500 * time.Millisecond)},
+		{Key: 2, Value: 2, UsedAt: now.Add(-500 * time.Millisecond)},
+	}))
+	require.True(t, c.Set(ctx, 3, 3)) // set before Restore, it is the freshest record
+	require.NoError(t, c.Restore(&buf))
+
+	var keys []int
+	for node := c.TTLTail; node != nil; node = node.TTLPrev {
+		keys = append(keys, node.Key)
+	}
+	require.Equal(t, []int{1, 2, 3}, keys)
+
+	time.Sleep(600 * time.Millisecond)
+	c.evictByTTL()
+	require.Equal(t, 2, c.Len())
+	_, ok := c.Get(ctx, 1)
+	require.False(t, ok)
+}
+
+func TestEvictByTTLMixed(t *testing.T) {
+	ctx := context.Background()
+	c := newCache[int, int](ctx, Config{TTL: 60})
+	defer c.Close()
+
+	for i := 0; i < 30; i++ {
+		switch i % 3 {
+		case 0:
+			require.True(t, c.SetWithTTL(ctx, i, i, 60))
+		case 1:
+			require.True(t, c.SetWithTTL(ctx, i, i, 1))
+		default:
+			require.True(t, c.Set(ctx, i, i))
+		}
+	}
+	require.True(t, c.SetWithTTL(ctx, 2, 2, 1)) // record moves from TTL list to heap
+	require.True(t, c.Set(ctx, 1, 1))           // and back
+	time.Sleep(1100 * time.Millisecond)
+
+	c.evictByTTL()
+	require.Equal(t, 20, c.Len())
+	_, ok := c.Get(ctx, 2)
+	require.False(t, ok)
+	_, ok = c.Get(ctx, 1)
+	require.True(t, ok)
+	require.Len(t, c.TTLHeap, 10)
+	require.Equal(t, uint64(10), c.CustomTTL)
+}
+
+func TestNegativeCaching(t *testing.T) {
+	ctx := context.Background()
+	errNotFound := errors.New("not found")
+	errUnavailable := errors.New("unavailable")
+	c, err := NewCache[int, int](ctx, Config{
+		NegativeTTL: 60,
+		IsNegative: func(err error) bool {
+			return errors.Is(err, errNotFound)
+		},
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	var calls int32
+	loader := func(ctx context.Context, key int) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		if key == 1 {
+			return 0, errNotFound
+		}
+		return 0, errUnavailable
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err = c.GetOrLoad(ctx, 1, loader)
+		require.ErrorIs(t, err, errNotFound)
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	_, ok := c.Get(ctx, 1)
+	require.False(t, ok)
+
+	for i := 0; i < 3; i++ {
+		_, err = c.GetOrLoad(ctx, 2, loader)
+		require.ErrorIs(t, err, errUnavailable)
+	}
+	require.Equal(t, int32(4), atomic.LoadInt32(&calls))
+
+	optIn, err := NewCache[int, int](ctx, Config{NegativeTTL: 60}) // without IsNegative errors are not cached
+	require.NoError(t, err)
+	defer optIn.Close()
+	for i := 0; i < 2; i++ {
+		_, err = optIn.GetOrLoad(ctx, 1, loader)
+		require.ErrorIs(t, err, errNotFound)
+	}
+	require.Equal(t, int32(6), atomic.LoadInt32(&calls))
+}
+
+func TestRefreshAhead(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewCache[int, int](ctx, Config{TTL: 60, RefreshAhead: 60})
+	require.NoError(t, err)
+	defer c.Close()
+
+	var calls int32
+	c.SetLoader(func(ctx context.Context, key int) (int, error) {
+		return int(atomic.AddInt32(&calls, 1)) * 10, nil
+	})
+	require.True(t, c.Set(ctx, 1, 1))
+
+	value, ok := c.Get(ctx, 1) // current value is returned, reload is started
+	require.True(t, ok)
+	require.Equal(t, 1, *value)
+	require.Eventually(t, func() bool {
+		value, ok := c.Get(ctx, 1)
+		return ok && *value >= 10
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestServeStale(t *testing.T) {
+	ctx := context.Background()
+	errUnavailable := errors.New("unavailable")
+	c, err := NewCache[int, int](ctx, Config{MaxStale: 60})
+	require.NoError(t, err)
+	defer c.Close()
+
+	loader := func(ctx context.Context, key int) (int, error) {
+		return 0, errUnavailable
+	}
+	require.True(t, c.SetWithTTL(ctx, 1, 1, 1))
+	time.Sleep(1100 * time.Millisecond)
+
+	_, ok := c.Get(ctx, 1) // expired records are not returned by Get
+	require.False(t, ok)
+	value, err := c.GetOrLoad(ctx, 1, loader)
+	require.NoError(t, err)
+	require.Equal(t, 1, *value)
+
+	_, err = c.GetOrLoad(ctx, 2, loader)
+	require.ErrorIs(t, err, errUnavailable)
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	ctx := context.Background()
+	for _, codec := range []Codec{GobCodec{}, JSONCodec{}} {
+		src, err := NewShardedCache[int, string](ctx, Config{TTL: 60, Codec: codec}, 4, nil)
+		require.NoError(t, err)
+		for i := 0; i < 10; i++ {
+			require.True(t, src.Set(ctx, i, strconv.Itoa(i)))
+		}
+		require.True(t, src.SetWithTTL(ctx, 10, "expired", 1))
+		time.Sleep(1100 * time.Millisecond)
+
+		var buf bytes.Buffer
+		require.NoError(t, src.Snapshot(&buf))
+		_ = src.Close()
+
+		dst, err := NewCache[int, string](ctx, Config{TTL: 60, Codec: codec})
+		require.NoError(t, err)
+		require.True(t, dst.Set(ctx, 0, "fresh"))
+		require.NoError(t, dst.Restore(&buf))
+		for i := 1; i < 10; i++ {
+			value, ok := dst.Get(ctx, i)
+			require.True(t, ok)
+			require.Equal(t, strconv.Itoa(i), *value)
+		}
+		value, ok := dst.Get(ctx, 0) // existing records are not overwritten
+		require.True(t, ok)
+		require.Equal(t, "fresh", *value)
+		_, ok = dst.Get(ctx, 10)
+		require.False(t, ok)
+		require.Equal(t, uint64(10), dst.Stats().Size)
+
+		dst.(*cache[int, string]).Lock.RLock()
+		usedAt := dst.(*cache[int, string]).Storage[1].UsedAt
+		dst.(*cache[int, string]).Lock.RUnlock()
+		require.Greater(t, time.Since(usedAt), time.Second) // TTL timestamps are kept
+		_ = dst.Close()
+	}
+}
+
+func TestBulk(t *testing.T) {
+	ctx := context.Background()
+	plain, err := NewCache[int, int](ctx, Config{})
+	require.NoError(t, err)
+	sharded, err := NewShardedCache[int, int](ctx, Config{}, 4, nil)
+	require.NoError(t, err)
+
+	for _, c := range []Cache[int, int]{plain, sharded} {
+		require.Equal(t, 10, c.SetMany(ctx, map[int]int{0: 0, 1: 10, 2: 20, 3: 30, 4: 40, 5: 50, 6: 60, 7: 70, 8: 80, 9: 90}))
+		require.Equal(t, 10, c.Len())
+
+		found, missing := c.GetMany(ctx, []int{1, 5, 10, 11})
+		require.Equal(t, map[int]int{1: 10, 5: 50}, found)
+		require.ElementsMatch(t, []int{10, 11}, missing)
+
+		keys := c.Keys()
+		require.ElementsMatch(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, keys)
+
+		sum, calls := 0, 0
+		c.Range(func(key, value int) bool {
+			sum += value
+			calls++
+			return true
+		})
+		require.Equal(t, 450, sum)
+		require.Equal(t, 10, calls)
+
+		calls = 0
+		c.Range(func(key, value int) bool {
+			calls++
+			return calls < 3
+		})
+		require.Equal(t, 3, calls)
+		_ = c.Close()
+	}
+}
+
+func TestTieredCache(t *testing.T) {
+	ctx := context.Background()
+	server, err := redistest.NewServer()
+	require.NoError(t, err)
+	defer server.Close()
+	client := redis.New(redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	var calls int32
+	loader := func(ctx context.Context, key int) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return strconv.Itoa(key), nil
+	}
+	newReplica := func() Cache[int, string] {
+		l1, err := NewCache[int, string](ctx, Config{})
+		require.NoError(t, err)
+		return NewTieredCache[int, string](l1, client, TieredConfig{Prefix: "products:", TTL: 60})
+	}
+	first, second := newReplica(), newReplica()
+	defer first.Close()
+	defer second.Close()
+
+	value, err := first.GetOrLoad(ctx, 1, loader)
+	require.NoError(t, err)
+	require.Equal(t, "1", *value)
+	value, err = second.GetOrLoad(ctx, 1, loader) // loaded from L2 by other replica
+	require.NoError(t, err)
+	require.Equal(t, "1", *value)
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	require.True(t, first.Set(ctx, 2, "two"))
+	value, ok := second.Get(ctx, 2)
+	require.True(t, ok)
+	require.Equal(t, "two", *value)
+	require.Equal(t, 2, second.Len()) // values read from L2 are saved to L1
+
+	found, missing := second.GetMany(ctx, []int{1, 2, 3})
+	require.Equal(t, map[int]string{1: "1", 2: "two"}, found)
+	require.Equal(t, []int{3}, missing)
+
+	require.True(t, second.Invalidate(ctx, 2))
+	require.Equal(t, 1, server.Commands("DEL"))
+	third := newReplica()
+	defer third.Close()
+	_, ok = third.Get(ctx, 2)
+	require.False(t, ok)
+
+	require.NoError(t, client.Set(ctx, "carts:1", []byte("1"), 0))
+	require.NoError(t, first.Clear(ctx))
+	_, ok = third.Get(ctx, 1) // cleared in L2 too
+	require.False(t, ok)
+	_, ok, err = client.Get(ctx, "carts:1") // keys without prefix stay
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestTieredCacheGetManyBatch(t *testing.T) {
+	ctx := context.Background()
+	server, err := redistest.NewServer()
+	require.NoError(t, err)
+	defer server.Close()
+	client := redis.New(redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	newReplica := func() Cache[int, string] {
+		l1, err := NewCache[int, string](ctx, Config{})
+		require.NoError(t, err)
+		return NewTieredCache[int, string](l1, client, TieredConfig{TTL: 60})
+	}
+	first, second := newReplica(), newReplica()
+	defer first.Close()
+	defer second.Close()
+	for i := 1; i <= 3; i++ {
+		require.True(t, first.Set(ctx, i, strconv.Itoa(i)))
+	}
+
+	found, missing := second.GetMany(ctx, []int{1, 2, 3, 4, 5})
+	require.Equal(t, map[int]string{1: "1", 2: "2", 3: "3"}, found)
+	require.ElementsMatch(t, []int{4, 5}, missing)
+	require.Equal(t, 1, server.Commands("MGET")) // all L1 misses are read with one L2 call
+	require.Zero(t, server.Commands("GET"))
+	require.Equal(t, 3, second.Len())
+}
+
+func TestTieredCacheRefreshAhead(t *testing.T) {
+	ctx := context.Background()
+	server, err := redistest.NewServer()
+	require.NoError(t, err)
+	defer server.Close()
+	client := redis.New(redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	l1, err := NewCache[int, int](ctx, Config{TTL: 60, RefreshAhead: 60})
+	require.NoError(t, err)
+	c := NewTieredCache[int, int](l1, client, TieredConfig{TTL: 600})
+	defer c.Close()
+
+	var calls int32
+	c.SetLoader(func(ctx context.Context, key int) (int, error) {
+		return int(atomic.AddInt32(&calls, 1)) * 10, nil
+	})
+	require.True(t, c.Set(ctx, 1, 1)) // old value is in L2 too
+
+	value, ok := c.Get(ctx, 1)
+	require.True(t, ok)
+	require.Equal(t, 1, *value)
+	require.Eventually(t, func() bool { // reload isn't served by stale L2 value
+		value, ok := c.Get(ctx, 1)
+		return ok && *value >= 10
+	}, time.Second, 10*time.Millisecond)
+	require.Zero(t, server.Commands("GET"))
+}
+
+func TestTieredCacheBackendDown(t *testing.T) {
+	ctx := context.Background()
+	server, err := redistest.NewServer()
+	require.NoError(t, err)
+	client := redis.New(redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	require.NoError(t, server.Close())
+
+	var l2Errors int32
+	l1, err := NewCache[int, string](ctx, Config{})
+	require.NoError(t, err)
+	c := NewTieredCache[int, string](l1, client, TieredConfig{OnError: func(err error) {
+		atomic.AddInt32(&l2Errors, 1)
+	}})
+	defer c.Close()
+
+	value, err := c.GetOrLoad(ctx, 1, func(ctx context.Context, key int) (string, error) {
+		return "1", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "1", *value)
+	require.Equal(t, int32(2), atomic.LoadInt32(&l2Errors)) // failed read and write
+	value, ok := c.Get(ctx, 1)
+	require.True(t, ok)
+	require.Equal(t, "1", *value)
+}
+
+func TestTieredCacheBackendHangs(t *testing.T) {
+	ctx := context.Background()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() { // accepts connections and never replies
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	client := redis.New(redis.Options{Addr: listener.Addr().String()})
+	defer client.Close()
+
+	var l2Errors int32
+	l1, err := NewCache[int, string](ctx, Config{})
+	require.NoError(t, err)
+	c := NewTieredCache[int, string](l1, client, TieredConfig{Timeout: 50 * time.Millisecond, OnError: func(err error) {
+		atomic.AddInt32(&l2Errors, 1)
+	}})
+	defer c.Close()
+
+	start := time.Now()
+	value, err := c.GetOrLoad(ctx, 1, func(ctx context.Context, key int) (string, error) {
+		return "1", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "1", *value)
+	require.Less(t, time.Since(start), time.Second)
+	require.Equal(t, int32(2), atomic.LoadInt32(&l2Errors)) // read and write timed out
+}
+
+func TestShardedCache(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewShardedCache[int, int](ctx, Config{MaxSize: 64, Type: LRUCache}, 8, nil)
+	require.NoError(t, err)
+	defer c.Close()
+
+	for i := 0; i < 1000; i++ {
+		require.True(t, c.Set(ctx, i, i))
+	}
+	hits := 0
+	for i := 0; i < 1000; i++ {
+		if value, ok := c.Get(ctx, i); ok {
+			require.Equal(t, i, *value)
+			hits++
+		}
+	}
+	require.LessOrEqual(t, hits, 64)
+	require.Greater(t, hits, 0)
+
+	require.NoError(t, c.Clear(ctx))
+	_, ok := c.Get(ctx, 999)
+	require.False(t, ok)
+}
+
+func TestShardedCacheMaxCost(t *testing.T) {
+	ctx := context.Background()
+	config := Config{MaxCost: 64, Type: LRUCache, Cost: func(key, value any) uint64 { return 2 }}
+	c, err := NewShardedCache[int, int](ctx, config, 8, nil)
+	require.NoError(t, err)
+	defer c.Close()
+
+	for i := 0; i < 1000; i++ {
+		require.True(t, c.Set(ctx, i, i))
+	}
+	require.LessOrEqual(t, c.Stats().Cost, config.MaxCost)
+	require.Greater(t, c.Stats().Cost, uint64(0))
+}
+
+const benchKeys = 1024
+
+func newBenchCaches(b *testing.B) map[string]Cache[int, int] {
+	ctx := context.Background()
+	config := Config{MaxSize: benchKeys, Type: LRUCache}
+	plain, err := NewCache[int, int](ctx, config)
+	require.NoError(b, err)
+	sharded, err := NewShardedCache[int, int](ctx, config, 16, nil)
+	require.NoError(b, err)
+	b.Cleanup(func() {
+		_ = plain.Close()
+		_ = sharded.Close()
+	})
+	return map[string]Cache[int, int]{
+		"plain":   plain,
+		"sharded": sharded,
+	}
+}
+
+func BenchmarkGetParallel(b *testing.B) {
+	ctx := context.Background()
+	for name, c := range newBenchCaches(b) {
+		for i := 0; i < benchKeys/2; i++ {
+			c.Set(ctx, i, i)
+		}
+		b.Run(name, func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				i := rand.Int()
+				for pb.Next() {
+					c.Get(ctx, i%(benchKeys/2))
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkSetGetParallel(b *testing.B) {
+	ctx := context.Background()
+	for name, c := range newBenchCaches(b) {
+		b.Run(name, func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				i := rand.Int()
+				for pb.Next() {
+					key := i % (benchKeys * 2)
+					if _, ok := c.Get(ctx, key); !ok {
+						c.Set(ctx, key, i)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// Prometheus collector for cache statistics
+// Collector reads cache Stats on every scrape, so metrics are always consistent with cache state
+// All metrics have constant label "cache" with name of cache, so several caches can be registered in one registry:
+//
+//	prometheus.MustRegister(cache.NewCollector("products", productsCache))
+
+package cache
+
+import "github.com/prometheus/client_golang/prometheus"
+
+type StatsProvider interface { // anything that can report cache statistics, implemented by all caches in this package
+	Stats() Stats
+}
+
+type Collector struct { // prometheus.Collector for cache statistics
+	cache     StatsProvider
+	hits      *prometheus.Desc
+	misses    *prometheus.Desc
+	inserts   *prometheus.Desc
+	rejected  *prometheus.Desc
+	evictions *prometheus.Desc
+	size      *prometheus.Desc
+	cost      *prometheus.Desc
+}
+
+func NewCollector(name string, cache StatsProvider) *Collector {
+	labels := prometheus.Labels{"cache": name}
+	desc := func(metric string, help string, variableLabels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("route256", "cache", metric), help, variableLabels, labels)
+	}
+	return &Collector{
+		cache:     cache,
+		hits:      desc("hits_total", "Number of cache lookups served from cache"),
+		misses:    desc("misses_total", "Number of cache lookups not found in cache"),
+		inserts:   desc("inserts_total", "Number of records added to cache"),
+		rejected:  desc("rejected_sets_total", "Number of Set calls rejected because cache is full"),
+		evictions: desc("evictions_total", "Number of records removed or replaced in cache by reason", "reason"),
+		size:      desc("size", "Current number of records in cache"),
+		cost:      desc("cost", "Current total cost of records in cache"),
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.inserts
+	ch <- c.rejected
+	ch <- c.evictions
+	ch <- c.size
+	ch <- c.cost
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.cache.Stats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.inserts, prometheus.CounterValue, float64(stats.Inserts))
+	ch <- prometheus.MustNewConstMetric(c.rejected, prometheus.CounterValue, float64(stats.Rejected))
+	for reason, count := range stats.Evictions {
+		ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(count), EvictReason(reason).String())
+	}
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(stats.Size))
+	ch <- prometheus.MustNewConstMetric(c.cost, prometheus.GaugeValue, float64(stats.Cost))
+}
@@ -0,0 +1,139 @@
+// Read-through loading for cache with request coalescing
+// When key is missing in cache, GetOrLoad calls loader function to get value and saves it in cache
+// Concurrent GetOrLoad calls for the same missing key are coalesced: loader is called only once,
+// all callers wait for it and share its result or error
+// Loader is called with context which keeps values of the first caller context, but not its cancellation and deadline,
+// so cancelled first caller doesn't fail other callers, the call is limited by Config.LoadTimeout instead
+// Callers except the first one stop waiting when their own context is done, the first caller waits for loader to finish
+// Load does the same for key already reported missing by Get or GetMany, it doesn't count the lookup in stats and TinyLFU sketch again
+// Loader errors selected by Config.IsNegative are cached for Config.NegativeTTL seconds, GetOrLoad returns cached error without calling loader
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const defaultLoadTimeout = 10 * time.Second
+
+var errLoaderPanic = errors.New("cache loader panicked") // Returned to callers waiting for loader call which panicked
+
+type Loader[KeyT comparable, ValueT any] func(ctx context.Context, key KeyT) (ValueT, error) // Function used to load missing value
+
+type call[ValueT any] struct { // In-flight loader call shared by all callers waiting for the same key
+	done  chan struct{} // Closed when loader call is finished
+	value ValueT        // Loaded value, valid after done is closed
+	err   error         // Loader error, valid after done is closed
+}
+
+type loadGroup[KeyT comparable, ValueT any] struct { // Set of in-flight loader calls by key
+	lock  sync.Mutex
+	calls map[KeyT]*call[ValueT]
+}
+
+func (g *loadGroup[KeyT, ValueT]) running(key KeyT) bool { // checks if loader call for key is in flight
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	_, ok := g.calls[key]
+	return ok
+}
+
+func (g *loadGroup[KeyT, ValueT]) do(ctx context.Context, key KeyT, fn func() (ValueT, error)) (ValueT, error) { // calls fn once for all concurrent callers with the same key
+	g.lock.Lock()
+	if g.calls == nil {
+		g.calls = make(map[KeyT]*call[ValueT])
+	}
+	cl, ok := g.calls[key]
+	if !ok {
+		cl = &call[ValueT]{done: make(chan struct{})}
+		g.calls[key] = cl
+		g.lock.Unlock()
+
+		g.run(key, cl, fn)
+		return cl.value, cl.err
+	}
+	g.lock.Unlock()
+
+	select {
+	case <-ctx.Done():
+		var empty ValueT
+		return empty, ctx.Err()
+	case <-cl.done:
+		return cl.value, cl.err
+	}
+}
+
+func (g *loadGroup[KeyT, ValueT]) run(key KeyT, cl *call[ValueT], fn func() (ValueT, error)) { // calls fn and releases waiting callers even if fn panics
+	defer func() {
+		g.lock.Lock()
+		delete(g.calls, key)
+		g.lock.Unlock()
+		close(cl.done)
+	}()
+	cl.err = errLoaderPanic // replaced by result of fn unless it panics
+	cl.value, cl.err = fn()
+}
+
+type detachedContext struct { // context with values of parent context, but without its cancellation and deadline
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (c *cache[KeyT, ValueT]) loadTimeout() time.Duration {
+	c.ConfigLock.RLock()
+	defer c.ConfigLock.RUnlock()
+
+	if c.Config.LoadTimeout <= 0 {
+		return defaultLoadTimeout
+	}
+	return c.Config.LoadTimeout
+}
+
+func (c *cache[KeyT, ValueT]) GetOrLoad(ctx context.Context, key KeyT, loader Loader[KeyT, ValueT]) (*ValueT, error) { // return value from cache, if not in cache loads it with loader and saves to cache
+	if value, ok, err := c.lookup(key); ok {
+		c.stats.lookup(true)
+		return value, err
+	}
+	c.stats.lookup(false)
+	return c.load(ctx, key, loader)
+}
+
+func (c *cache[KeyT, ValueT]) Load(ctx context.Context, key KeyT, loader Loader[KeyT, ValueT]) (*ValueT, error) { // GetOrLoad for key already counted as miss by Get or GetMany
+	return c.load(ctx, key, loader)
+}
+
+func (c *cache[KeyT, ValueT]) load(ctx context.Context, key KeyT, loader Loader[KeyT, ValueT]) (*ValueT, error) { // loads value with coalescing of concurrent calls, lookup is not counted
+	value, err := c.loads.do(ctx, key, func() (ValueT, error) {
+		if value, ok, err := c.get(key); ok { // value could be loaded by previous call while we were waiting for lock
+			if err != nil {
+				return *new(ValueT), err
+			}
+			return *value, nil
+		}
+		loadCtx, cancel := context.WithTimeout(detachedContext{ctx}, c.loadTimeout())
+		defer cancel()
+		value, err := loader(loadCtx, key)
+		if err != nil {
+			if c.setError(key, err) {
+				return value, err
+			}
+			if stale, ok := c.stale(key); ok { // serve stale value while loader fails
+				return stale, nil
+			}
+			return value, err
+		}
+		c.set(key, value, nil, 0)
+		return value, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
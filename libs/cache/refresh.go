@@ -0,0 +1,54 @@
+// Refresh-ahead and stale-while-revalidate for cache
+// When RefreshAhead is set in configuration and loader is registered with SetLoader,
+// Get returns current value of record which expires in less than RefreshAhead seconds
+// and starts one asynchronous reload of it, so frequently used records never expire
+// Asynchronous reloads are coalesced with GetOrLoad calls for the same key and stop when cache context is done
+//
+// When MaxStale is set, expired records are kept in cache for MaxStale more seconds
+// Get doesn't return them, but GetOrLoad returns stale value if loader fails, so loader outage doesn't fail callers
+
+package cache
+
+func (c *cache[KeyT, ValueT]) SetLoader(loader Loader[KeyT, ValueT]) { // registers loader for refresh-ahead
+	c.ConfigLock.Lock()
+	defer c.ConfigLock.Unlock()
+
+	c.loader = loader
+}
+
+func (c *cache[KeyT, ValueT]) needsRefresh(node *Node[KeyT, ValueT]) bool { // checks if node expires in less than RefreshAhead seconds
+	ttl := c.ttl(node)
+	return c.loader != nil && c.Config.RefreshAhead > 0 && ttl > 0 && node.Err == nil &&
+		c.age(node)+c.Config.RefreshAhead >= ttl
+}
+
+func (c *cache[KeyT, ValueT]) refreshAsync(key KeyT) { // starts reload of key with registered loader if it is not loading already
+	if c.loads.running(key) {
+		return
+	}
+	loader := c.loader
+	go func() {
+		_, _ = c.loads.do(c.ctx, key, func() (ValueT, error) {
+			value, err := loader(c.ctx, key)
+			if err != nil { // current value stays in cache until expiration
+				return value, err
+			}
+			c.set(key, value, nil, 0)
+			return value, nil
+		})
+	}()
+}
+
+func (c *cache[KeyT, ValueT]) stale(key KeyT) (ValueT, bool) { // returns value of expired record if it is not older than MaxStale
+	c.ConfigLock.RLock()
+	defer c.ConfigLock.RUnlock()
+
+	c.Lock.RLock()
+	defer c.Lock.RUnlock()
+
+	if node, ok := c.Storage[key]; ok && node.Err == nil && !c.removable(node) {
+		return node.Value, true
+	}
+	var empty ValueT
+	return empty, false
+}
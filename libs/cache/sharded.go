@@ -0,0 +1,143 @@
+// Sharded cache implementation
+// Keys are distributed by hash between N independent caches (shards), each shard has its own locks, lists and size budget
+// MaxSize and MaxCost from configuration are divided evenly between shards, so eviction works per shard and is only approximately global
+// All other configuration parameters are applied to each shard as is
+//
+// Sharded cache is useful when cache is used concurrently by many goroutines:
+// each Get in plain cache takes write lock to update LRU/LFU lists, in sharded cache only one shard is locked
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+)
+
+type Hasher[KeyT comparable] func(key KeyT) uint64 // Function used to select shard for key
+
+type shardedCache[KeyT comparable, ValueT any] struct {
+	shards []*cache[KeyT, ValueT] // Independent caches
+	hash   Hasher[KeyT]           // Hash function for shard selection
+}
+
+// NewShardedCache creates cache with shardsCount independent shards
+// If hash is nil, default hash function is used, it is fast for integer and string keys and uses fmt formatting for other types
+func NewShardedCache[KeyT comparable, ValueT any](ctx context.Context, config Config, shardsCount uint, hash Hasher[KeyT]) (Cache[KeyT, ValueT], error) {
+	if shardsCount == 0 {
+		return nil, fmt.Errorf("shards count must be positive")
+	}
+	if hash == nil {
+		hash = defaultHash[KeyT]
+	}
+	c := &shardedCache[KeyT, ValueT]{
+		shards: make([]*cache[KeyT, ValueT], shardsCount),
+		hash:   hash,
+	}
+	shardConfig := c.shardConfig(config)
+	for i := range c.shards {
+		c.shards[i] = newCache[KeyT, ValueT](ctx, shardConfig)
+	}
+	return c, nil
+}
+
+func (c *shardedCache[KeyT, ValueT]) shardConfig(config Config) Config { // configuration for one shard, MaxSize and MaxCost are divided between shards rounding up
+	count := uint64(len(c.shards))
+	config.MaxSize = (config.MaxSize + count - 1) / count
+	config.MaxCost = (config.MaxCost + count - 1) / count
+	return config
+}
+
+func (c *shardedCache[KeyT, ValueT]) shard(key KeyT) *cache[KeyT, ValueT] {
+	return c.shards[c.hash(key)%uint64(len(c.shards))]
+}
+
+func (c *shardedCache[KeyT, ValueT]) Set(ctx context.Context, key KeyT, value ValueT) bool {
+	return c.shard(key).Set(ctx, key, value)
+}
+
+func (c *shardedCache[KeyT, ValueT]) SetWithTTL(ctx context.Context, key KeyT, value ValueT, ttl uint64) bool {
+	return c.shard(key).SetWithTTL(ctx, key, value, ttl)
+}
+
+func (c *shardedCache[KeyT, ValueT]) Get(ctx context.Context, key KeyT) (*ValueT, bool) {
+	return c.shard(key).Get(ctx, key)
+}
+
+func (c *shardedCache[KeyT, ValueT]) GetOrLoad(ctx context.Context, key KeyT, loader Loader[KeyT, ValueT]) (*ValueT, error) {
+	return c.shard(key).GetOrLoad(ctx, key, loader)
+}
+
+func (c *shardedCache[KeyT, ValueT]) Load(ctx context.Context, key KeyT, loader Loader[KeyT, ValueT]) (*ValueT, error) {
+	return c.shard(key).Load(ctx, key, loader)
+}
+
+func (c *shardedCache[KeyT, ValueT]) Invalidate(ctx context.Context, key KeyT) bool {
+	return c.shard(key).Invalidate(ctx, key)
+}
+
+func (c *shardedCache[KeyT, ValueT]) SetConfig(ctx context.Context, config Config) error {
+	shardConfig := c.shardConfig(config)
+	for _, shard := range c.shards {
+		if err := shard.SetConfig(ctx, shardConfig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *shardedCache[KeyT, ValueT]) SetLoader(loader Loader[KeyT, ValueT]) {
+	for _, shard := range c.shards {
+		shard.SetLoader(loader)
+	}
+}
+
+func (c *shardedCache[KeyT, ValueT]) Clear(ctx context.Context) error {
+	for _, shard := range c.shards {
+		if err := shard.Clear(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *shardedCache[KeyT, ValueT]) Close() error {
+	for _, shard := range c.shards {
+		_ = shard.Close()
+	}
+	return nil
+}
+
+func defaultHash[KeyT comparable](key KeyT) uint64 { // hash for shard selection, integers are mixed with splitmix64 finalizer, strings hashed with FNV-1a
+	switch k := any(key).(type) {
+	case int:
+		return mix64(uint64(k))
+	case int32:
+		return mix64(uint64(k))
+	case int64:
+		return mix64(uint64(k))
+	case uint:
+		return mix64(uint64(k))
+	case uint32:
+		return mix64(uint64(k))
+	case uint64:
+		return mix64(k)
+	case string:
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(k))
+		return h.Sum64()
+	default:
+		h := fnv.New64a()
+		_, _ = fmt.Fprint(h, k)
+		return h.Sum64()
+	}
+}
+
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
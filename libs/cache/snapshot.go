@@ -0,0 +1,173 @@
+// Cache snapshot and restore
+// Snapshot writes all records of cache to io.Writer, Restore reads them back, so cache can survive service restart
+// Records are encoded with Codec from configuration, gob is used by default, JSON codec is available for readable snapshots
+// Snapshot keeps record values, individual TTL and time of last Set, so restored records expire at the same time as original ones,
+// they are inserted to TTL order by time of last Set, not as fresh records
+// Records expired before Restore are skipped, cached loader errors and LFU counters are not saved
+// Records already present in cache are not overwritten by Restore, they are considered fresher than snapshot
+//
+// Snapshot format: header with format version followed by records from oldest to newest, encoded one by one
+
+package cache
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+const snapshotVersion = 1 // Version of snapshot format
+
+type Encoder interface { // Encodes values to stream, implemented by gob.Encoder and json.Encoder
+	Encode(v any) error
+}
+
+type Decoder interface { // Decodes values from stream, implemented by gob.Decoder and json.Decoder
+	Decode(v any) error
+}
+
+type Codec interface { // Snapshot encoding
+	NewEncoder(w io.Writer) Encoder
+	NewDecoder(r io.Reader) Decoder
+}
+
+type GobCodec struct{} // Binary encoding with encoding/gob, default codec
+
+func (GobCodec) NewEncoder(w io.Writer) Encoder { return gob.NewEncoder(w) }
+func (GobCodec) NewDecoder(r io.Reader) Decoder { return gob.NewDecoder(r) }
+
+type JSONCodec struct{} // Text encoding with encoding/json
+
+func (JSONCodec) NewEncoder(w io.Writer) Encoder { return json.NewEncoder(w) }
+func (JSONCodec) NewDecoder(r io.Reader) Decoder { return json.NewDecoder(r) }
+
+type snapshotHeader struct {
+	Version uint
+}
+
+type snapshotEntry[KeyT comparable, ValueT any] struct { // Saved record
+	Key    KeyT
+	Value  ValueT
+	TTL    uint64    // Individual TTL, 0 - TTL from configuration
+	UsedAt time.Time // Time of last Set
+}
+
+func (c *cache[KeyT, ValueT]) Snapshot(w io.Writer) error { // writes all records to w
+	return writeSnapshot(w, c.codec(), c.entries())
+}
+
+func (c *cache[KeyT, ValueT]) Restore(r io.Reader) error { // reads records written by Snapshot from r and adds them to cache
+	return readSnapshot(r, c.codec(), c.restoreEntry)
+}
+
+func (c *cache[KeyT, ValueT]) codec() Codec {
+	c.ConfigLock.RLock()
+	defer c.ConfigLock.RUnlock()
+
+	if c.Config.Codec == nil {
+		return GobCodec{}
+	}
+	return c.Config.Codec
+}
+
+func (c *cache[KeyT, ValueT]) entries() []snapshotEntry[KeyT, ValueT] { // copies records from oldest to newest
+	c.Lock.RLock()
+	entries := make([]snapshotEntry[KeyT, ValueT], 0, c.Size)
+	for node := c.TTLTail; node != nil; node = node.TTLPrev {
+		entries = appendEntry(entries, node)
+	}
+	for _, node := range c.TTLHeap {
+		entries = appendEntry(entries, node)
+	}
+	c.Lock.RUnlock()
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].UsedAt.Before(entries[j].UsedAt)
+	})
+	return entries
+}
+
+func appendEntry[KeyT comparable, ValueT any](entries []snapshotEntry[KeyT, ValueT], node *Node[KeyT, ValueT]) []snapshotEntry[KeyT, ValueT] {
+	if node.Err != nil {
+		return entries
+	}
+	return append(entries, snapshotEntry[KeyT, ValueT]{
+		Key:    node.Key,
+		Value:  node.Value,
+		TTL:    node.TTL,
+		UsedAt: node.UsedAt,
+	})
+}
+
+func (c *cache[KeyT, ValueT]) restoreEntry(entry snapshotEntry[KeyT, ValueT]) { // adds saved record with its time of last Set if it is not expired and not in cache
+	c.ConfigLock.RLock()
+	ttl := entry.TTL
+	if ttl == 0 {
+		ttl = c.Config.TTL
+	}
+	expired := ttl > 0 && uint64(time.Since(entry.UsedAt).Seconds()) >= ttl+c.Config.MaxStale
+	c.ConfigLock.RUnlock()
+	if expired {
+		return
+	}
+
+	c.Lock.RLock()
+	_, exists := c.Storage[entry.Key]
+	c.Lock.RUnlock()
+	if !exists {
+		c.setAt(entry.Key, entry.Value, nil, entry.TTL, entry.UsedAt)
+	}
+}
+
+func writeSnapshot[KeyT comparable, ValueT any](w io.Writer, codec Codec, entries []snapshotEntry[KeyT, ValueT]) error {
+	encoder := codec.NewEncoder(w)
+	if err := encoder.Encode(snapshotHeader{Version: snapshotVersion}); err != nil {
+		return fmt.Errorf("encoding snapshot header: %w", err)
+	}
+	for i := range entries {
+		if err := encoder.Encode(&entries[i]); err != nil {
+			return fmt.Errorf("encoding snapshot record: %w", err)
+		}
+	}
+	return nil
+}
+
+func readSnapshot[KeyT comparable, ValueT any](r io.Reader, codec Codec, restore func(entry snapshotEntry[KeyT, ValueT])) error {
+	decoder := codec.NewDecoder(r)
+	var header snapshotHeader
+	if err := decoder.Decode(&header); err != nil {
+		return fmt.Errorf("decoding snapshot header: %w", err)
+	}
+	if header.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", header.Version)
+	}
+	for {
+		var entry snapshotEntry[KeyT, ValueT]
+		if err := decoder.Decode(&entry); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("decoding snapshot record: %w", err)
+		}
+		restore(entry)
+	}
+}
+
+func (c *shardedCache[KeyT, ValueT]) Snapshot(w io.Writer) error { // writes records of all shards to w, snapshot can be restored to cache with any number of shards
+	var entries []snapshotEntry[KeyT, ValueT]
+	for _, shard := range c.shards {
+		entries = append(entries, shard.entries()...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].UsedAt.Before(entries[j].UsedAt)
+	})
+	return writeSnapshot(w, c.shards[0].codec(), entries)
+}
+
+func (c *shardedCache[KeyT, ValueT]) Restore(r io.Reader) error {
+	return readSnapshot(r, c.shards[0].codec(), func(entry snapshotEntry[KeyT, ValueT]) {
+		c.shard(entry.Key).restoreEntry(entry)
+	})
+}
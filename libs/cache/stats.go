@@ -0,0 +1,77 @@
+// Cache usage statistics
+// Counters are updated with atomic operations and don't need cache locks
+// Statistics can be exported to Prometheus with Collector (see collector.go)
+
+package cache
+
+import "sync/atomic"
+
+const evictReasonsCount = int(EvictReplaced) + 1 // Number of eviction reasons
+
+type Stats struct { // snapshot of cache usage statistics
+	Hits      uint64                    // Get and GetOrLoad calls served from cache
+	Misses    uint64                    // Get and GetOrLoad calls not found in cache
+	Inserts   uint64                    // New records added to cache
+	Rejected  uint64                    // Set calls rejected because cache is full
+	Evictions [evictReasonsCount]uint64 // Removed and replaced records by EvictReason
+	Size      uint64                    // Current cache size
+	Cost      uint64                    // Current total cost of records
+}
+
+type stats struct { // cache usage counters
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	inserts   atomic.Uint64
+	rejected  atomic.Uint64
+	evictions [evictReasonsCount]atomic.Uint64
+}
+
+func (s *stats) lookup(found bool) { // counts hit or miss
+	if found {
+		s.hits.Add(1)
+	} else {
+		s.misses.Add(1)
+	}
+}
+
+func (s *stats) snapshot() Stats {
+	result := Stats{
+		Hits:     s.hits.Load(),
+		Misses:   s.misses.Load(),
+		Inserts:  s.inserts.Load(),
+		Rejected: s.rejected.Load(),
+	}
+	for i := range s.evictions {
+		result.Evictions[i] = s.evictions[i].Load()
+	}
+	return result
+}
+
+func (s *Stats) add(other Stats) { // sums statistics of shards
+	s.Hits += other.Hits
+	s.Misses += other.Misses
+	s.Inserts += other.Inserts
+	s.Rejected += other.Rejected
+	for i := range s.Evictions {
+		s.Evictions[i] += other.Evictions[i]
+	}
+	s.Size += other.Size
+	s.Cost += other.Cost
+}
+
+func (c *cache[KeyT, ValueT]) Stats() Stats { // returns usage statistics of cache
+	result := c.stats.snapshot()
+	c.Lock.RLock()
+	result.Size = c.Size
+	result.Cost = c.TotalCost
+	c.Lock.RUnlock()
+	return result
+}
+
+func (c *shardedCache[KeyT, ValueT]) Stats() Stats { // returns usage statistics summed for all shards
+	var result Stats
+	for _, shard := range c.shards {
+		result.add(shard.Stats())
+	}
+	return result
+}
@@ -0,0 +1,262 @@
+// Two-tier cache: in-process cache (L1) in front of remote storage shared by service replicas (L2)
+// Reads fall through: L1 is checked first, on miss value is read from L2 and saved to L1, on L2 miss loader is called
+// Writes go to both tiers, Invalidate removes record from both tiers
+// L2 is any storage implementing Backend interface, for example Redis client from route256/libs/redis
+// Values are encoded for L2 with Codec from TieredConfig, keys are formatted with fmt and prefixed with TieredConfig.Prefix
+//
+// L2 is optional for correctness: its errors are reported to OnError callback and treated as misses,
+// so L2 outage only increases number of loader calls
+// Every L2 call on read and write paths is limited by TieredConfig.Timeout, so unresponsive L2 delays cache misses by at most this timeout
+// Clear removes all L2 records with Prefix, so replicas don't refill L1 with cleared values, it is limited by ctx only
+// Snapshot, Restore, Len, Keys, Range, SetConfig and Stats work with L1 only
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+const defaultTieredTimeout = 100 * time.Millisecond
+
+type Backend interface { // Remote storage for second cache tier
+	Get(ctx context.Context, key string) ([]byte, bool, error)              // Returns false if key doesn't exist
+	GetMany(ctx context.Context, keys ...string) (map[string][]byte, error) // Returns values of existing keys only
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	DeletePrefix(ctx context.Context, prefix string) error // Removes all keys starting with prefix, empty prefix - all keys
+}
+
+type TieredConfig struct { // second tier parameters
+	Prefix  string          // Prefix of L2 keys, allows to share L2 between caches
+	TTL     uint64          // Time to live for L2 records in seconds, 0 - indefinite
+	Codec   Codec           // Encoding of L2 values, nil - gob
+	Timeout time.Duration   // Timeout of every L2 call, 0 - 100ms
+	OnError func(err error) // Called on L2 errors, nil - errors are ignored
+}
+
+type tieredCache[KeyT comparable, ValueT any] struct {
+	l1     Cache[KeyT, ValueT]
+	l2     Backend
+	config TieredConfig
+}
+
+// NewTieredCache creates cache with l1 as first tier and l2 as second tier
+// Cache takes ownership of l1 and closes it on Close, l2 must be closed by caller
+func NewTieredCache[KeyT comparable, ValueT any](l1 Cache[KeyT, ValueT], l2 Backend, config TieredConfig) Cache[KeyT, ValueT] {
+	if config.Codec == nil {
+		config.Codec = GobCodec{}
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTieredTimeout
+	}
+	return &tieredCache[KeyT, ValueT]{
+		l1:     l1,
+		l2:     l2,
+		config: config,
+	}
+}
+
+func (c *tieredCache[KeyT, ValueT]) Set(ctx context.Context, key KeyT, value ValueT) bool {
+	c.store(ctx, key, value, c.config.TTL)
+	return c.l1.Set(ctx, key, value)
+}
+
+func (c *tieredCache[KeyT, ValueT]) SetWithTTL(ctx context.Context, key KeyT, value ValueT, ttl uint64) bool {
+	c.store(ctx, key, value, ttl)
+	return c.l1.SetWithTTL(ctx, key, value, ttl)
+}
+
+func (c *tieredCache[KeyT, ValueT]) Get(ctx context.Context, key KeyT) (*ValueT, bool) {
+	if value, ok := c.l1.Get(ctx, key); ok {
+		return value, true
+	}
+	value, ok := c.fetch(ctx, key)
+	if !ok {
+		return nil, false
+	}
+	c.l1.Set(ctx, key, value)
+	return &value, true
+}
+
+func (c *tieredCache[KeyT, ValueT]) GetMany(ctx context.Context, keys []KeyT) (map[KeyT]ValueT, []KeyT) {
+	found, missing := c.l1.GetMany(ctx, keys)
+	if len(missing) == 0 {
+		return found, missing
+	}
+	fetched := c.fetchMany(ctx, missing)
+	stillMissing := missing[:0]
+	for _, key := range missing {
+		if value, ok := fetched[key]; ok {
+			found[key] = value
+		} else {
+			stillMissing = append(stillMissing, key)
+		}
+	}
+	c.l1.SetMany(ctx, fetched)
+	return found, stillMissing
+}
+
+func (c *tieredCache[KeyT, ValueT]) SetMany(ctx context.Context, items map[KeyT]ValueT) int {
+	for key, value := range items {
+		c.store(ctx, key, value, c.config.TTL)
+	}
+	return c.l1.SetMany(ctx, items)
+}
+
+func (c *tieredCache[KeyT, ValueT]) GetOrLoad(ctx context.Context, key KeyT, loader Loader[KeyT, ValueT]) (*ValueT, error) { // loads missing value from L2, then with loader, loads are coalesced by L1
+	return c.l1.GetOrLoad(ctx, key, c.loader(loader))
+}
+
+func (c *tieredCache[KeyT, ValueT]) Load(ctx context.Context, key KeyT, loader Loader[KeyT, ValueT]) (*ValueT, error) { // key was already read from L2 by Get or GetMany, so loader is called without L2 read
+	return c.l1.Load(ctx, key, c.refreshLoader(loader))
+}
+
+func (c *tieredCache[KeyT, ValueT]) Invalidate(ctx context.Context, key KeyT) bool {
+	l2ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+	if err := c.l2.Delete(l2ctx, c.key(key)); err != nil {
+		c.onError(fmt.Errorf("deleting %v from L2: %w", key, err))
+	}
+	return c.l1.Invalidate(ctx, key)
+}
+
+func (c *tieredCache[KeyT, ValueT]) SetConfig(ctx context.Context, config Config) error {
+	return c.l1.SetConfig(ctx, config)
+}
+
+func (c *tieredCache[KeyT, ValueT]) SetLoader(loader Loader[KeyT, ValueT]) { // refresh-ahead reloads skip L2 read, it would return the same value, and update L2
+	c.l1.SetLoader(c.refreshLoader(loader))
+}
+
+func (c *tieredCache[KeyT, ValueT]) Snapshot(w io.Writer) error {
+	return c.l1.Snapshot(w)
+}
+
+func (c *tieredCache[KeyT, ValueT]) Restore(r io.Reader) error {
+	return c.l1.Restore(r)
+}
+
+func (c *tieredCache[KeyT, ValueT]) Len() int {
+	return c.l1.Len()
+}
+
+func (c *tieredCache[KeyT, ValueT]) Keys() []KeyT {
+	return c.l1.Keys()
+}
+
+func (c *tieredCache[KeyT, ValueT]) Range(fn func(key KeyT, value ValueT) bool) {
+	c.l1.Range(fn)
+}
+
+func (c *tieredCache[KeyT, ValueT]) Clear(ctx context.Context) error { // L2 is cleared first, so concurrent L1 misses don't read cleared values back
+	l2Err := c.l2.DeletePrefix(ctx, c.config.Prefix)
+	if err := c.l1.Clear(ctx); err != nil {
+		return err
+	}
+	if l2Err != nil {
+		return fmt.Errorf("clearing L2: %w", l2Err)
+	}
+	return nil
+}
+
+func (c *tieredCache[KeyT, ValueT]) Close() error {
+	return c.l1.Close()
+}
+
+func (c *tieredCache[KeyT, ValueT]) Stats() Stats {
+	return c.l1.Stats()
+}
+
+func (c *tieredCache[KeyT, ValueT]) loader(loader Loader[KeyT, ValueT]) Loader[KeyT, ValueT] { // wraps loader with L2 read before and L2 write after it
+	return func(ctx context.Context, key KeyT) (ValueT, error) {
+		if value, ok := c.fetch(ctx, key); ok {
+			return value, nil
+		}
+		return c.refreshLoader(loader)(ctx, key)
+	}
+}
+
+func (c *tieredCache[KeyT, ValueT]) refreshLoader(loader Loader[KeyT, ValueT]) Loader[KeyT, ValueT] { // wraps loader with L2 write after it
+	return func(ctx context.Context, key KeyT) (ValueT, error) {
+		value, err := loader(ctx, key)
+		if err != nil {
+			return value, err
+		}
+		c.store(ctx, key, value, c.config.TTL)
+		return value, nil
+	}
+}
+
+func (c *tieredCache[KeyT, ValueT]) fetch(ctx context.Context, key KeyT) (ValueT, bool) { // reads value from L2
+	var value ValueT
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+	data, ok, err := c.l2.Get(ctx, c.key(key))
+	if err != nil {
+		c.onError(fmt.Errorf("reading %v from L2: %w", key, err))
+		return value, false
+	}
+	if !ok {
+		return value, false
+	}
+	if err := c.config.Codec.NewDecoder(bytes.NewReader(data)).Decode(&value); err != nil {
+		c.onError(fmt.Errorf("decoding %v from L2: %w", key, err))
+		return value, false
+	}
+	return value, true
+}
+
+func (c *tieredCache[KeyT, ValueT]) fetchMany(ctx context.Context, keys []KeyT) map[KeyT]ValueT { // reads values from L2 with one call
+	values := make(map[KeyT]ValueT, len(keys))
+	l2Keys := make([]string, len(keys))
+	for i, key := range keys {
+		l2Keys[i] = c.key(key)
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+	data, err := c.l2.GetMany(ctx, l2Keys...)
+	if err != nil {
+		c.onError(fmt.Errorf("reading %v keys from L2: %w", len(keys), err))
+		return values
+	}
+	for i, key := range keys {
+		encoded, ok := data[l2Keys[i]]
+		if !ok {
+			continue
+		}
+		var value ValueT
+		if err := c.config.Codec.NewDecoder(bytes.NewReader(encoded)).Decode(&value); err != nil {
+			c.onError(fmt.Errorf("decoding %v from L2: %w", key, err))
+			continue
+		}
+		values[key] = value
+	}
+	return values
+}
+
+func (c *tieredCache[KeyT, ValueT]) store(ctx context.Context, key KeyT, value ValueT, ttl uint64) { // writes value to L2
+	var buf bytes.Buffer
+	if err := c.config.Codec.NewEncoder(&buf).Encode(value); err != nil {
+		c.onError(fmt.Errorf("encoding %v for L2: %w", key, err))
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+	if err := c.l2.Set(ctx, c.key(key), buf.Bytes(), time.Duration(ttl)*time.Second); err != nil {
+		c.onError(fmt.Errorf("writing %v to L2: %w", key, err))
+	}
+}
+
+func (c *tieredCache[KeyT, ValueT]) key(key KeyT) string {
+	return c.config.Prefix + fmt.Sprint(key)
+}
+
+func (c *tieredCache[KeyT, ValueT]) onError(err error) {
+	if c.config.OnError != nil {
+		c.config.OnError(err)
+	}
+}
@@ -0,0 +1,127 @@
+// TinyLFU admission policy in front of LRU
+// TinyLFUCache type uses LRU list for eviction candidates, but new record is admitted to full cache
+// only if it was used more frequently than the records which have to be evicted for it
+// There is no admission window (as in W-TinyLFU): new record competes for admission on its first insertion,
+// so sparse bursts of new keys are rejected until their frequency exceeds frequency of LRU tail
+// Frequencies are estimated with count-min sketch: 4 rows of 4-bit counters (stored in bytes), indexed by key hash
+// Each row has 4 counters per record of MaxSize to keep estimation error low
+// Sketch counts every user lookup (Get, GetMany, GetOrLoad) including misses, so frequency of records absent in cache is known too
+// Set and loader results saved by GetOrLoad are not counted, so miss followed by Set counts as one access,
+// record which was never looked up is not admitted to full cache
+// All counters are halved after 10 * MaxSize accesses, so records popular long ago are forgotten (aging)
+//
+// Policy is scan resistant: one pass over many unique keys can't wash out frequently used records
+// All operations are O(1). Sketch exists and is updated only for TinyLFUCache type, other types don't pay for access counting,
+// switching to TinyLFUCache with SetConfig starts with empty sketch
+
+package cache
+
+import "sync"
+
+const (
+	sketchDepth          = 4    // Number of sketch rows
+	sketchMaxCount       = 15   // Maximum value of 4-bit counter
+	sketchWidthFactor    = 4    // Number of counters in sketch row per record of MaxSize
+	sketchMinSize        = 16   // Minimum MaxSize used for sketch sizing
+	sketchDefaultMaxSize = 1024 // MaxSize used for sketch sizing when MaxSize is not set
+	sketchSampleFactor   = 10   // Counters are halved after sketchSampleFactor * MaxSize accesses
+)
+
+type sketch struct { // count-min sketch for frequency estimation
+	lock       sync.Mutex
+	table      []uint8 // Counters, sketchDepth rows of width counters
+	width      uint64  // Number of counters in row, power of 2
+	sampleSize uint64  // Number of increments between agings
+	additions  uint64  // Number of increments since last aging
+}
+
+func newSketchFor(config Config) *sketch { // sketch for cache configuration, nil if TinyLFU admission is not used
+	if config.Type != TinyLFUCache {
+		return nil
+	}
+	return newSketch(config.MaxSize)
+}
+
+func newSketch(maxSize uint64) *sketch {
+	if maxSize == 0 {
+		maxSize = sketchDefaultMaxSize
+	}
+	if maxSize < sketchMinSize {
+		maxSize = sketchMinSize
+	}
+	width := uint64(1)
+	for width < sketchWidthFactor*maxSize {
+		width <<= 1
+	}
+	return &sketch{
+		table:      make([]uint8, sketchDepth*width),
+		width:      width,
+		sampleSize: sketchSampleFactor * maxSize,
+	}
+}
+
+func (s *sketch) index(hash uint64, row uint64) uint64 { // counter index in row, double hashing with two halves of hash
+	h1, h2 := hash&0xffffffff, hash>>32
+	return row*s.width + (h1+row*h2)&(s.width-1)
+}
+
+func (s *sketch) increment(hash uint64) { // conservative update: only minimal counters are incremented
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	min := s.estimateLocked(hash)
+	if min < sketchMaxCount {
+		for row := uint64(0); row < sketchDepth; row++ {
+			if i := s.index(hash, row); s.table[i] == min {
+				s.table[i]++
+			}
+		}
+	}
+	s.additions++
+	if s.additions >= s.sampleSize { // aging
+		for i := range s.table {
+			s.table[i] >>= 1
+		}
+		s.additions /= 2
+	}
+}
+
+func (s *sketch) estimate(hash uint64) uint8 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.estimateLocked(hash)
+}
+
+func (s *sketch) estimateLocked(hash uint64) uint8 {
+	min := uint8(sketchMaxCount)
+	for row := uint64(0); row < sketchDepth; row++ {
+		if count := s.table[s.index(hash, row)]; count < min {
+			min = count
+		}
+	}
+	return min
+}
+
+func sketchHash[KeyT comparable](key KeyT) uint64 { // key hash for sketch, remixed to be independent from shard selection
+	return mix64(defaultHash(key) ^ 0x9e3779b97f4a7c15)
+}
+
+func (c *cache[KeyT, ValueT]) recordAccess(key KeyT) { // counts key access in frequency sketch if it exists, must be called under ConfigLock
+	if c.sketch != nil {
+		c.sketch.increment(sketchHash(key))
+	}
+}
+
+func (c *cache[KeyT, ValueT]) evictByTinyLFU(key KeyT, cost uint64) { // evicts LRU records until there is room for key, if key is used more frequently than them
+	candidate := c.sketch.estimate(sketchHash(key))
+	c.Lock.Lock()
+	for c.LRUTail != nil && c.noRoom(key, cost) {
+		victim := c.LRUTail
+		if victim.Key != key && c.sketch.estimate(sketchHash(victim.Key)) >= candidate { // victim is not less valuable, key is not admitted
+			break
+		}
+		c.removeNode(victim, EvictTinyLFU)
+	}
+	c.unlockAndNotify()
+}
@@ -0,0 +1,52 @@
+// Min-heap of records with individual TTL ordered by expiration time
+// Records with TTL from configuration are kept in TTL list, which is sorted by expiration time because all of them have the same TTL,
+// records with individual TTL (set by SetWithTTL or cached loader errors) expire in arbitrary order and are kept in this heap instead,
+// so TTL eviction checks only expired records from the tail of TTL list and the top of the heap
+
+package cache
+
+import (
+	"container/heap"
+	"time"
+)
+
+type ttlHeap[KeyT comparable, ValueT any] []*Node[KeyT, ValueT]
+
+func (h ttlHeap[KeyT, ValueT]) Len() int { return len(h) }
+
+func (h ttlHeap[KeyT, ValueT]) Less(i, j int) bool {
+	return expiresAt(h[i]).Before(expiresAt(h[j]))
+}
+
+func (h ttlHeap[KeyT, ValueT]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].TTLIndex = i
+	h[j].TTLIndex = j
+}
+
+func (h *ttlHeap[KeyT, ValueT]) Push(x any) {
+	node := x.(*Node[KeyT, ValueT])
+	node.TTLIndex = len(*h)
+	*h = append(*h, node)
+}
+
+func (h *ttlHeap[KeyT, ValueT]) Pop() any {
+	old := *h
+	node := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	node.TTLIndex = -1
+	return node
+}
+
+func expiresAt[KeyT comparable, ValueT any](node *Node[KeyT, ValueT]) time.Time { // expiration time of record with individual TTL
+	return node.UsedAt.Add(time.Duration(node.TTL) * time.Second)
+}
+
+func (c *cache[KeyT, ValueT]) pushTTL(node *Node[KeyT, ValueT]) { // adds record with individual TTL to heap
+	heap.Push(&c.TTLHeap, node)
+}
+
+func (c *cache[KeyT, ValueT]) removeHeapTTL(node *Node[KeyT, ValueT]) { // removes record with individual TTL from heap
+	heap.Remove(&c.TTLHeap, node.TTLIndex)
+}
@@ -0,0 +1,284 @@
+// Cluster-wide cache invalidation over Kafka
+// Publisher sends Invalidate(keys) and Clear commands for named cache to Kafka topic as JSON messages
+// Subscriber reads all partitions of the topic and applies commands to local libs/cache instance,
+// so every service replica runs its own Subscriber without consumer group: each replica has to see every command
+// Replica has no committed offsets, so commands published while it is stopped are lost unless Run is given the time to replay them from:
+// replica starting with empty cache doesn't need them and reads from the newest offset,
+// replica restoring cache from snapshot has to replay commands published since the snapshot was saved
+// Commands older than topic retention can't be replayed, snapshot older than retention may contain invalidated records
+//
+// One topic can be shared by several caches, commands are filtered by cache name
+// Commands of one cache are published with cache name as message key, so they are kept in order in one partition
+
+package cachebus
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"route256/libs/cache"
+	log "route256/libs/logger"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type Op string // Cache command
+
+const (
+	OpInvalidate Op = "invalidate" // Remove keys from cache
+	OpClear      Op = "clear"      // Remove all records from cache
+)
+
+type Config struct { // bus parameters
+	Brokers           []string // Kafka brokers
+	Topic             string   // Topic for commands, it is created with one partition if it doesn't exist
+	ReplicationFactor int16    // Replication factor of created topic, 0 - number of brokers, but not more than 3
+}
+
+type Command[KeyT comparable] struct { // Message published to topic
+	Cache string `json:"cache"`          // Cache name
+	Op    Op     `json:"op"`             // Command
+	Keys  []KeyT `json:"keys,omitempty"` // Keys for OpInvalidate
+}
+
+type Publisher[KeyT comparable] struct {
+	topic    string
+	cache    string
+	producer sarama.SyncProducer
+}
+
+// NewPublisher creates publisher of commands for cache with name cacheName, topic is created if it doesn't exist
+func NewPublisher[KeyT comparable](config Config, cacheName string) (*Publisher[KeyT], error) {
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.Version = sarama.MaxVersion
+	if err := ensureTopic(config, saramaConfig); err != nil {
+		return nil, err
+	}
+
+	saramaConfig.Producer.Partitioner = sarama.NewHashPartitioner
+	saramaConfig.Producer.RequiredAcks = sarama.WaitForAll
+	saramaConfig.Producer.Return.Successes = true
+
+	producer, err := sarama.NewSyncProducer(config.Brokers, saramaConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating cache bus producer")
+	}
+	return newPublisher[KeyT](producer, config.Topic, cacheName), nil
+}
+
+func newPublisher[KeyT comparable](producer sarama.SyncProducer, topic, cacheName string) *Publisher[KeyT] {
+	return &Publisher[KeyT]{
+		topic:    topic,
+		cache:    cacheName,
+		producer: producer,
+	}
+}
+
+// Invalidate publishes command to remove keys from cache on all replicas
+func (p *Publisher[KeyT]) Invalidate(ctx context.Context, keys ...KeyT) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return p.publish(ctx, Command[KeyT]{Cache: p.cache, Op: OpInvalidate, Keys: keys})
+}
+
+// Clear publishes command to remove all records from cache on all replicas
+func (p *Publisher[KeyT]) Clear(ctx context.Context) error {
+	return p.publish(ctx, Command[KeyT]{Cache: p.cache, Op: OpClear})
+}
+
+func (p *Publisher[KeyT]) Close() error {
+	return p.producer.Close()
+}
+
+func (p *Publisher[KeyT]) publish(ctx context.Context, command Command[KeyT]) error {
+	data, err := json.Marshal(command)
+	if err != nil {
+		return errors.Wrap(err, "encoding cache command")
+	}
+	partition, offset, err := p.producer.SendMessage(&sarama.ProducerMessage{
+		Topic:     p.topic,
+		Key:       sarama.StringEncoder(p.cache),
+		Value:     sarama.ByteEncoder(data),
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "publishing cache command")
+	}
+	log.Debug(ctx, "cache command published", zap.String("cache", p.cache), zap.String("op", string(command.Op)), zap.Int32("partition", partition), zap.Int64("offset", offset))
+	return nil
+}
+
+type offsetFunc func(topic string, partition int32, time int64) (int64, error) // returns offset of first message with timestamp >= time in milliseconds
+
+type Subscriber[KeyT comparable, ValueT any] struct {
+	topic    string
+	cache    string
+	target   cache.Cache[KeyT, ValueT]
+	client   sarama.Client
+	consumer sarama.Consumer
+	offset   offsetFunc
+}
+
+// NewSubscriber creates subscriber applying commands for cache with name cacheName to target, topic is created if it doesn't exist
+func NewSubscriber[KeyT comparable, ValueT any](config Config, cacheName string, target cache.Cache[KeyT, ValueT]) (*Subscriber[KeyT, ValueT], error) {
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.Version = sarama.MaxVersion
+	if err := ensureTopic(config, saramaConfig); err != nil {
+		return nil, err
+	}
+
+	saramaConfig.Consumer.Return.Errors = true
+	client, err := sarama.NewClient(config.Brokers, saramaConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "connecting to kafka")
+	}
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, errors.Wrap(err, "creating cache bus consumer")
+	}
+	s := newSubscriber(consumer, client.GetOffset, config.Topic, cacheName, target)
+	s.client = client
+	return s, nil
+}
+
+func newSubscriber[KeyT comparable, ValueT any](consumer sarama.Consumer, offset offsetFunc, topic, cacheName string, target cache.Cache[KeyT, ValueT]) *Subscriber[KeyT, ValueT] {
+	return &Subscriber[KeyT, ValueT]{
+		topic:    topic,
+		cache:    cacheName,
+		target:   target,
+		consumer: consumer,
+		offset:   offset,
+	}
+}
+
+// Run applies commands from all partitions of topic until ctx is done
+// Commands published since the time since are replayed first, zero since - only new commands are applied
+func (s *Subscriber[KeyT, ValueT]) Run(ctx context.Context, since time.Time) error {
+	partitions, err := s.consumer.Partitions(s.topic)
+	if err != nil {
+		return errors.Wrap(err, "getting cache bus partitions")
+	}
+	consumers := make([]sarama.PartitionConsumer, 0, len(partitions))
+	defer func() {
+		for _, pc := range consumers {
+			_ = pc.Close()
+		}
+	}()
+	for _, partition := range partitions {
+		offset := sarama.OffsetNewest
+		if !since.IsZero() {
+			if offset, err = s.offset(s.topic, partition, since.UnixMilli()); err != nil { // OffsetNewest if there are no messages since
+				return errors.Wrapf(err, "getting cache bus partition %d offset", partition)
+			}
+		}
+		pc, err := s.consumer.ConsumePartition(s.topic, partition, offset)
+		if err != nil {
+			return errors.Wrapf(err, "consuming cache bus partition %d", partition)
+		}
+		consumers = append(consumers, pc)
+	}
+
+	var wg sync.WaitGroup
+	for _, pc := range consumers {
+		wg.Add(1)
+		go func(pc sarama.PartitionConsumer) {
+			defer wg.Done()
+			s.consume(ctx, pc)
+		}(pc)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (s *Subscriber[KeyT, ValueT]) Close() error {
+	err := s.consumer.Close()
+	if s.client != nil {
+		_ = s.client.Close()
+	}
+	return err
+}
+
+func (s *Subscriber[KeyT, ValueT]) consume(ctx context.Context, pc sarama.PartitionConsumer) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-pc.Messages():
+			if !ok {
+				return
+			}
+			if err := s.apply(ctx, message.Value); err != nil {
+				log.Error(ctx, "applying cache command", zap.String("cache", s.cache), zap.Error(err))
+			}
+		case err, ok := <-pc.Errors():
+			if !ok {
+				return
+			}
+			log.Error(ctx, "consuming cache commands", zap.String("cache", s.cache), zap.Error(err))
+		}
+	}
+}
+
+func (s *Subscriber[KeyT, ValueT]) apply(ctx context.Context, data []byte) error { // applies command to target cache if command is for it
+	var command Command[KeyT]
+	if err := json.Unmarshal(data, &command); err != nil {
+		return errors.Wrap(err, "decoding cache command")
+	}
+	if command.Cache != s.cache {
+		return nil
+	}
+	switch command.Op {
+	case OpInvalidate:
+		for _, key := range command.Keys {
+			s.target.Invalidate(ctx, key)
+		}
+	case OpClear:
+		if err := s.target.Clear(ctx); err != nil {
+			return err
+		}
+	default:
+		return errors.Errorf("unknown cache command %q", command.Op)
+	}
+	log.Debug(ctx, "cache command applied", zap.String("cache", s.cache), zap.String("op", string(command.Op)), zap.Int("keys", len(command.Keys)))
+	return nil
+}
+
+func ensureTopic(config Config, saramaConfig *sarama.Config) error { // creates topic with one partition if it doesn't exist
+	admin, err := sarama.NewClusterAdmin(config.Brokers, saramaConfig)
+	if err != nil {
+		return errors.Wrap(err, "connecting to kafka")
+	}
+	defer admin.Close()
+
+	topics, err := admin.ListTopics()
+	if err != nil {
+		return errors.Wrap(err, "listing kafka topics")
+	}
+	if _, ok := topics[config.Topic]; ok {
+		return nil
+	}
+	err = admin.CreateTopic(config.Topic, &sarama.TopicDetail{
+		NumPartitions:     1,
+		ReplicationFactor: config.replicationFactor(),
+	}, false)
+	if err != nil && !errors.Is(err, sarama.ErrTopicAlreadyExists) {
+		return errors.Wrap(err, "creating cache bus topic")
+	}
+	return nil
+}
+
+func (c Config) replicationFactor() int16 {
+	if c.ReplicationFactor > 0 {
+		return c.ReplicationFactor
+	}
+	if len(c.Brokers) < 3 {
+		return int16(len(c.Brokers))
+	}
+	return 3
+}
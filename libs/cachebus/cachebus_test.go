@@ -0,0 +1,115 @@
+package cachebus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"route256/libs/cache"
+	log "route256/libs/logger"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBus(t *testing.T) {
+	log.Init(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	products, err := cache.NewCache[uint32, string](ctx, cache.Config{})
+	require.NoError(t, err)
+	others, err := cache.NewCache[uint32, string](ctx, cache.Config{})
+	require.NoError(t, err)
+	for sku := uint32(1); sku <= 3; sku++ {
+		products.Set(ctx, sku, "product")
+		others.Set(ctx, sku, "other")
+	}
+
+	var published [][]byte
+	producer := mocks.NewSyncProducer(t, nil)
+	for i := 0; i < 2; i++ {
+		producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(message *sarama.ProducerMessage) error {
+			require.Equal(t, "cache-commands", message.Topic)
+			value, err := message.Value.Encode()
+			require.NoError(t, err)
+			published = append(published, value)
+			return nil
+		})
+	}
+	publisher := newPublisher[uint32](producer, "cache-commands", "products")
+	require.NoError(t, publisher.Invalidate(ctx, 1, 2))
+	require.NoError(t, publisher.Invalidate(ctx))
+	require.NoError(t, publisher.Clear(ctx))
+	require.NoError(t, publisher.Close())
+
+	consumer := mocks.NewConsumer(t, nil)
+	consumer.SetTopicMetadata(map[string][]int32{"cache-commands": {0}})
+	partition := consumer.ExpectConsumePartition("cache-commands", 0, sarama.OffsetNewest)
+	subscriber := newSubscriber[uint32, string](consumer, nil, "cache-commands", "products", products)
+	done := make(chan error)
+	go func() {
+		done <- subscriber.Run(ctx, time.Time{})
+	}()
+
+	partition.YieldMessage(&sarama.ConsumerMessage{Value: published[0]})
+	require.Eventually(t, func() bool {
+		return products.Len() == 1
+	}, time.Second, 10*time.Millisecond)
+	_, ok := products.Get(ctx, 3)
+	require.True(t, ok)
+
+	partition.YieldMessage(&sarama.ConsumerMessage{Value: published[1]})
+	require.Eventually(t, func() bool {
+		return products.Len() == 0
+	}, time.Second, 10*time.Millisecond)
+
+	other := newSubscriber[uint32, string](nil, nil, "cache-commands", "others", others)
+	require.NoError(t, other.apply(ctx, published[1])) // commands for other caches are ignored
+	require.Equal(t, 3, others.Len())
+	require.Error(t, other.apply(ctx, []byte("not json")))
+
+	cancel()
+	require.NoError(t, <-done)
+	require.NoError(t, subscriber.Close())
+}
+
+func TestBusReplay(t *testing.T) {
+	log.Init(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	products, err := cache.NewCache[uint32, string](ctx, cache.Config{})
+	require.NoError(t, err)
+	products.Set(ctx, 1, "product")
+
+	since := time.Now().Add(-time.Minute)
+	offset := func(topic string, partition int32, time int64) (int64, error) {
+		require.Equal(t, since.UnixMilli(), time)
+		return 5, nil
+	}
+	consumer := mocks.NewConsumer(t, nil)
+	consumer.SetTopicMetadata(map[string][]int32{"cache-commands": {0}})
+	partition := consumer.ExpectConsumePartition("cache-commands", 0, 5) // replay starts from offset of since
+	subscriber := newSubscriber[uint32, string](consumer, offset, "cache-commands", "products", products)
+	done := make(chan error)
+	go func() {
+		done <- subscriber.Run(ctx, since)
+	}()
+
+	partition.YieldMessage(&sarama.ConsumerMessage{Value: []byte(`{"cache":"products","op":"clear"}`)})
+	require.Eventually(t, func() bool {
+		return products.Len() == 0
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	require.NoError(t, subscriber.Close())
+}
+
+func TestReplicationFactor(t *testing.T) {
+	require.Equal(t, int16(1), Config{Brokers: []string{"kafka1"}}.replicationFactor())
+	require.Equal(t, int16(3), Config{Brokers: []string{"kafka1", "kafka2", "kafka3", "kafka4"}}.replicationFactor())
+	require.Equal(t, int16(2), Config{Brokers: []string{"kafka1", "kafka2", "kafka3"}, ReplicationFactor: 2}.replicationFactor())
+}
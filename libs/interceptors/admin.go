@@ -0,0 +1,69 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/subtle"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AdminTokenHeader заголовок запроса с токеном администратора
+const AdminTokenHeader = "x-admin-token"
+
+// NewAdminInterceptor создает интерсептор, пропускающий запросы к административным методам methods только с токеном администратора
+// в заголовке x-admin-token, запросы без токена или с неверным токеном отклоняются с кодом PermissionDenied
+// Если token пустой, то административные методы отключены
+func NewAdminInterceptor(token string, methods ...string) grpc.UnaryServerInterceptor {
+	admin := make(map[string]struct{}, len(methods))
+	for _, method := range methods {
+		admin[method] = struct{}{}
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if _, ok := admin[info.FullMethod]; !ok {
+			return handler(ctx, req)
+		}
+		if token == "" {
+			return nil, status.Errorf(codes.PermissionDenied, "%s is disabled: admin token is not configured", info.FullMethod)
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, got := range md.Get(AdminTokenHeader) {
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+				return handler(ctx, req)
+			}
+		}
+		return nil, status.Errorf(codes.PermissionDenied, "%s requires admin token", info.FullMethod)
+	}
+}
+
+// NewAdminHTTPHandler защищает изменяющие запросы к административному HTTP API, например к уровню логов или запуску задач
+// Запросы GET и HEAD пропускаются без проверки, остальные пропускаются только с токеном администратора в заголовке x-admin-token,
+// если token пустой, то только с localhost, иначе отклоняются с кодом 403
+func NewAdminHTTPHandler(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if token == "" && isLoopback(r.RemoteAddr) ||
+			token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get(AdminTokenHeader)), []byte(token)) == 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		http.Error(w, "admin token required", http.StatusForbidden)
+	})
+}
+
+func isLoopback(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,64 @@
+package interceptors
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAdminInterceptor(t *testing.T) {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	call := func(interceptor grpc.UnaryServerInterceptor, method, token string) error {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(AdminTokenHeader, token))
+		}
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	interceptor := NewAdminInterceptor("secret", "/test/Admin")
+	require.NoError(t, call(interceptor, "/test/Admin", "secret"))
+	require.Equal(t, codes.PermissionDenied, status.Code(call(interceptor, "/test/Admin", "wrong")))
+	require.Equal(t, codes.PermissionDenied, status.Code(call(interceptor, "/test/Admin", "")))
+	require.NoError(t, call(interceptor, "/test/Public", ""))
+
+	disabled := NewAdminInterceptor("", "/test/Admin")
+	require.Equal(t, codes.PermissionDenied, status.Code(call(disabled, "/test/Admin", "")))
+}
+
+func TestAdminHTTPHandler(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	call := func(handler http.Handler, method, remoteAddr, token string) int {
+		r := httptest.NewRequest(method, "/log/level", nil)
+		r.RemoteAddr = remoteAddr
+		if token != "" {
+			r.Header.Set(AdminTokenHeader, token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	withToken := NewAdminHTTPHandler("secret", next)
+	require.Equal(t, http.StatusNoContent, call(withToken, http.MethodGet, "10.0.0.1:1234", ""))
+	require.Equal(t, http.StatusNoContent, call(withToken, http.MethodPut, "10.0.0.1:1234", "secret"))
+	require.Equal(t, http.StatusForbidden, call(withToken, http.MethodPut, "10.0.0.1:1234", "wrong"))
+	require.Equal(t, http.StatusForbidden, call(withToken, http.MethodPut, "127.0.0.1:1234", ""))
+
+	localOnly := NewAdminHTTPHandler("", next)
+	require.Equal(t, http.StatusNoContent, call(localOnly, http.MethodPost, "127.0.0.1:1234", ""))
+	require.Equal(t, http.StatusNoContent, call(localOnly, http.MethodPost, "[::1]:1234", ""))
+	require.Equal(t, http.StatusForbidden, call(localOnly, http.MethodPost, "10.0.0.1:1234", ""))
+}
@@ -11,8 +11,16 @@ import (
 	"time"
 )
 
+// orderRequest запрос, в котором передается ID заказа
+type orderRequest interface {
+	GetOrderID() int64
+}
+
+// LoggingInterceptor логирует запросы и добавляет в контекст поля логов запроса: метод, ID пользователя и заказа,
+// так что их содержат все логи обработчика, записанные с его ctx
 func LoggingInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	log.Debug("incoming GRPC request", zap.String("method", info.FullMethod), zap.Any("request", req))
+	ctx = log.With(ctx, requestFields(req, info)...)
+	log.Debug(ctx, "incoming GRPC request", zap.Any("request", req))
 	metrics.RequestsCounter.WithLabelValues(info.FullMethod).Inc()
 
 	timeStart := time.Now()
@@ -22,7 +30,7 @@ func LoggingInterceptor(ctx context.Context, req interface{}, info *grpc.UnarySe
 		if span := opentracing.SpanFromContext(ctx); span != nil {
 			ext.Error.Set(span, true)
 		}
-		log.Error(ctx, "Error handling GRPC request", zap.String("method", info.FullMethod), zap.Error(err))
+		log.Error(ctx, "Error handling GRPC request", zap.Error(err))
 		metrics.ResponseCounter.WithLabelValues("error").Inc()
 
 		elapsed := time.Since(timeStart)
@@ -31,7 +39,7 @@ func LoggingInterceptor(ctx context.Context, req interface{}, info *grpc.UnarySe
 		return nil, err
 	}
 
-	log.Debug("GRPC response", zap.String("method", info.FullMethod), zap.Any("response", res))
+	log.Debug(ctx, "GRPC response", zap.Any("response", res))
 	metrics.ResponseCounter.WithLabelValues("success", info.FullMethod).Inc()
 
 	elapsed := time.Since(timeStart)
@@ -39,3 +47,14 @@ func LoggingInterceptor(ctx context.Context, req interface{}, info *grpc.UnarySe
 
 	return res, nil
 }
+
+func requestFields(req interface{}, info *grpc.UnaryServerInfo) []zap.Field {
+	fields := []zap.Field{zap.String("method", info.FullMethod)}
+	if r, ok := req.(userRequest); ok {
+		fields = append(fields, zap.Int64("userID", r.GetUser()))
+	}
+	if r, ok := req.(orderRequest); ok {
+		fields = append(fields, zap.Int64("orderID", r.GetOrderID()))
+	}
+	return fields
+}
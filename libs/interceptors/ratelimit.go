@@ -0,0 +1,94 @@
+package interceptors
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"route256/libs/limiter"
+	log "route256/libs/logger"
+)
+
+// RetryAfterHeader заголовок ответа с количеством секунд, через которое запрос может быть выполнен
+const RetryAfterHeader = "retry-after"
+
+type RateLimit struct {
+	Rate  float64 `yaml:"rate"`  // Запросов в секунду
+	Burst int     `yaml:"burst"` // Запросов, которые могут пройти одновременно
+}
+
+type RateLimitConfig struct {
+	Methods map[string]RateLimit `yaml:"methods"` // Лимиты по FullMethod, методы, которых нет в списке, не ограничиваются
+	IdleTTL uint64               `yaml:"idleTTL"` // Время в секундах, через которое удаляется неиспользуемый лимитер
+}
+
+// userRequest запрос, в котором передается ID пользователя
+type userRequest interface {
+	GetUser() int64
+}
+
+type rateLimitKey struct {
+	method string
+	user   int64
+}
+
+var RateLimitedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "route256",
+	Subsystem: "grpc",
+	Name:      "rate_limited_total",
+},
+	[]string{"handler"},
+)
+
+// NewRateLimitInterceptor создает интерсептор, ограничивающий запросы к методам из config.Methods отдельно для каждого пользователя
+// Пользователь определяется по полю user запроса, запросы без этого поля ограничиваются общим для метода лимитом
+// Запросы сверх лимита отклоняются с кодом ResourceExhausted, в заголовке retry-after передается время до следующей попытки
+// В ctx передается контекст используемый для остановки удаления неиспользуемых лимитеров при graceful shutdown
+func NewRateLimitInterceptor(ctx context.Context, config RateLimitConfig) grpc.UnaryServerInterceptor {
+	limiters := limiter.NewKeyed(ctx, time.Duration(config.IdleTTL)*time.Second, func(key rateLimitKey) *limiter.TokenBucket {
+		limit := config.Methods[key.method]
+		return limiter.NewTokenBucket(limit.Rate, limit.Burst)
+	})
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if _, ok := config.Methods[info.FullMethod]; !ok {
+			return handler(ctx, req)
+		}
+		key := rateLimitKey{method: info.FullMethod}
+		if r, ok := req.(userRequest); ok {
+			key.user = r.GetUser()
+		}
+
+		bucket := limiters.Get(key)
+		if bucket.Allow() {
+			return handler(ctx, req)
+		}
+
+		RateLimitedCounter.WithLabelValues(info.FullMethod).Inc()
+		log.Debug(ctx, "request rate limited", requestFields(req, info)...)
+		retryAfter := retryAfterSeconds(bucket)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RetryAfterHeader, strconv.FormatInt(retryAfter, 10)))
+		return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded for %s, retry after %ds", info.FullMethod, retryAfter)
+	}
+}
+
+// retryAfterSeconds возвращает время в секундах до появления токена в корзине, но не меньше 1
+// Если корзина не пополняется, то возвращается 1
+func retryAfterSeconds(bucket *limiter.TokenBucket) int64 {
+	rate, _ := bucket.Limit()
+	if rate <= 0 {
+		return 1
+	}
+	retryAfter := int64(math.Ceil((1 - bucket.Tokens()) / rate))
+	if retryAfter < 1 {
+		return 1
+	}
+	return retryAfter
+}
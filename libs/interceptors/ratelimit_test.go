@@ -0,0 +1,75 @@
+package interceptors
+
+import (
+	"context"
+	"testing"
+
+	log "route256/libs/logger"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type testUserRequest struct {
+	user int64
+}
+
+func (r testUserRequest) GetUser() int64 {
+	return r.user
+}
+
+// headerStream collects response headers set with grpc.SetHeader
+type headerStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestRateLimitInterceptor(t *testing.T) {
+	log.Init(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interceptor := NewRateLimitInterceptor(ctx, RateLimitConfig{
+		Methods: map[string]RateLimit{
+			"/test/Limited": {Rate: 0, Burst: 2},    // bucket is never refilled, so test does not depend on time
+			"/test/Slow":    {Rate: 0.01, Burst: 1}, // one request per 100s
+		},
+		IdleTTL: 60,
+	})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	call := func(method string, req interface{}) (metadata.MD, error) {
+		stream := &headerStream{}
+		_, err := interceptor(grpc.NewContextWithServerTransportStream(ctx, stream), req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return stream.header, err
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := call("/test/Limited", testUserRequest{user: 1})
+		require.NoError(t, err)
+	}
+	header, err := call("/test/Limited", testUserRequest{user: 1})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, []string{"1"}, header.Get(RetryAfterHeader))
+
+	_, err = call("/test/Limited", testUserRequest{user: 2}) // users are limited separately
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, err = call("/test/Other", testUserRequest{user: 1}) // methods without limit
+		require.NoError(t, err)
+	}
+
+	_, err = call("/test/Slow", testUserRequest{user: 1})
+	require.NoError(t, err)
+	header, err = call("/test/Slow", testUserRequest{user: 1})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, []string{"100"}, header.Get(RetryAfterHeader))
+}
@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	log "route256/libs/logger"
+
+	"go.uber.org/zap"
+)
+
+// HTTP API администратора для задач Manager:
+//   GET  <prefix>             - статусы всех задач
+//   GET  <prefix>/<name>      - статус задачи
+//   POST <prefix>/<name>/run  - запуск задачи вне расписания, 409 если задача не запущена, уже выполняется
+//                               или реплика не является лидером для задачи с Leader
+// Имя задачи в пути передается в URL-кодировке, например /jobs/Unpayed%20orders/run
+
+// NewAdminHandler создает обработчик API, prefix - путь, на котором он зарегистрирован, например "/jobs"
+func NewAdminHandler(m *Manager, prefix string) http.Handler {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+		if path == "" {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			jobs := m.Jobs()
+			statuses := make([]Status, 0, len(jobs))
+			for _, job := range jobs {
+				statuses = append(statuses, job.Status())
+			}
+			writeJSON(r.Context(), w, statuses)
+			return
+		}
+
+		name, action := path, ""
+		if strings.HasSuffix(path, "/run") {
+			name, action = strings.TrimSuffix(path, "/run"), "run"
+		}
+		job, ok := m.Job(name)
+		if !ok {
+			http.Error(w, "job not found", http.StatusNotFound)
+			return
+		}
+		switch {
+		case action == "" && r.Method == http.MethodGet:
+			writeJSON(r.Context(), w, job.Status())
+		case action == "run" && r.Method == http.MethodPost:
+			if err := job.Trigger(); err != nil {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			log.Info(r.Context(), "job triggered by admin", zap.String("jobName", job.Name))
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func writeJSON(ctx context.Context, w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warn(ctx, "writing jobs admin response", zap.Error(err))
+	}
+}
@@ -0,0 +1,87 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	log "route256/libs/logger"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHandler(t *testing.T) {
+	log.Init(true)
+	ctx := context.Background()
+	var runs atomic.Int64
+	job := NewJob("Unpayed orders", func(ctx context.Context) error {
+		if runs.Add(1) == 2 {
+			return errors.New("failed")
+		}
+		return nil
+	}, time.Hour)
+	m := NewManager()
+	require.NoError(t, m.Add(job))
+	handler := NewAdminHandler(m, "/jobs/")
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+	require.Equal(t, http.StatusConflict, request(http.MethodPost, "/jobs/Unpayed%20orders/run").Code) // not started
+
+	require.NoError(t, m.Start(ctx))
+	for i := 0; i < 2; i++ {
+		require.Equal(t, http.StatusAccepted, request(http.MethodPost, "/jobs/Unpayed%20orders/run").Code)
+		require.Eventually(t, func() bool { return job.Status().Runs == uint64(i+1) }, time.Second, time.Millisecond)
+	}
+	require.NoError(t, m.Shutdown(ctx))
+
+	w := request(http.MethodGet, "/jobs")
+	require.Equal(t, http.StatusOK, w.Code)
+	var statuses []Status
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&statuses))
+	require.Len(t, statuses, 1)
+	require.Equal(t, "Unpayed orders", statuses[0].Name)
+	require.Equal(t, uint64(2), statuses[0].Runs)
+	require.Equal(t, uint64(1), statuses[0].Failures)
+	require.Equal(t, "failed", statuses[0].LastError)
+	require.False(t, statuses[0].LastStart.IsZero())
+
+	require.Equal(t, http.StatusOK, request(http.MethodGet, "/jobs/Unpayed%20orders").Code)
+	require.Equal(t, http.StatusNotFound, request(http.MethodGet, "/jobs/unknown").Code)
+	require.Equal(t, http.StatusMethodNotAllowed, request(http.MethodGet, "/jobs/Unpayed%20orders/run").Code)
+}
+
+func TestAdminHandlerNotLeader(t *testing.T) {
+	ctx := context.Background()
+	var runs atomic.Int64
+	job := NewJob("Send order notifications", func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}, time.Hour)
+	leader := &testLeader{}
+	job.Leader = leader
+	m := NewManager()
+	require.NoError(t, m.Add(job))
+	require.NoError(t, m.Start(ctx))
+	defer func() { _ = m.Shutdown(ctx) }()
+	handler := NewAdminHandler(m, "/jobs")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/jobs/Send%20order%20notifications/run", nil))
+	require.Equal(t, http.StatusConflict, w.Code)
+	require.ErrorIs(t, job.Trigger(), ErrNotLeader)
+
+	leader.leader.Store(true)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/jobs/Send%20order%20notifications/run", nil))
+	require.Equal(t, http.StatusAccepted, w.Code)
+	require.Eventually(t, func() bool { return runs.Load() == 1 }, time.Second, time.Millisecond)
+}
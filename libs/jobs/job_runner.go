@@ -2,17 +2,47 @@ package jobs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
+	"math/rand"
 	log "route256/libs/logger"
+	"sync"
 	"time"
 )
 
+// ErrNotLeader возвращается Trigger на реплике, которая не является лидером для задачи с Leader
+var ErrNotLeader = errors.New("replica is not leader")
+
+// OverlapPolicy определяет, что делать с запуском задачи, если предыдущий запуск еще выполняется
+type OverlapPolicy int
+
+const (
+	OverlapSkip       OverlapPolicy = iota // Пропустить запуск
+	OverlapQueue                           // Выполнить после текущего, несколько ожидающих запусков объединяются в один
+	OverlapConcurrent                      // Выполнить параллельно с текущим
+)
+
 type Job struct {
-	Name    string
-	Period  time.Duration
-	JobFunc func(ctx context.Context) error
-	ticker  *time.Ticker
+	Name       string
+	Period     time.Duration
+	Schedule   Schedule      // Расписание запуска, если nil, то задача запускается каждые Period
+	RunOnStart bool          // Запустить задачу сразу при Run, не дожидаясь расписания
+	Jitter     time.Duration // Случайная задержка каждого запуска от 0 до Jitter, чтобы реплики не запускали задачу одновременно
+	Leader     Leader        // Если задан, то задача singleton: запускается только на реплике-лидере
+	Retry      RetryPolicy   // Повторы при ошибке
+	Timeout    time.Duration // Таймаут одной попытки, если 0, то не ограничен
+	Overlap    OverlapPolicy // Запуск задачи, пока выполняется предыдущий
+	JobFunc    func(ctx context.Context) error
+
+	lock       sync.Mutex
+	cancel     context.CancelFunc // Останавливает расписание
+	cancelRuns context.CancelFunc // Прерывает выполняющиеся запуски
+	runsCtx    context.Context    // Контекст запусков, в т.ч. запущенных через Trigger
+	runs       sync.WaitGroup     // Выполняющиеся запуски
+	running    int
+	queued     bool
+	status     Status
 }
 
 func NewJob(name string, job func(ctx context.Context) error, period time.Duration) *Job {
@@ -23,39 +53,228 @@ func NewJob(name string, job func(ctx context.Context) error, period time.Durati
 	}
 }
 
+// NewCronJob создает задачу, запускаемую по cron выражению, например "0 3 * * *" - каждый день в 03:00 (см. ParseCron)
+func NewCronJob(name string, job func(ctx context.Context) error, spec string) (*Job, error) {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		return nil, fmt.Errorf("creating job %v: %w", name, err)
+	}
+	return &Job{
+		Name:     name,
+		Schedule: schedule,
+		JobFunc:  job,
+	}, nil
+}
+
+// Run запускает задачу по расписанию до завершения ctx или Stop
+// Запуски выполняются в отдельных горутинах с контекстом ctx, Stop их не прерывает
 func (job *Job) Run(ctx context.Context) error {
-	if job.ticker != nil {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+
+	if job.cancel != nil {
 		return fmt.Errorf("this job is already running: %v", job.Name)
 	}
-
-	job.ticker = time.NewTicker(job.Period)
-	go func(ctx context.Context) {
-		for {
-			select {
-			case <-ctx.Done():
-				job.ticker.Stop()
-				return
-			case t := <-job.ticker.C:
-				log.Debug("Running job", zap.String("jobName", job.Name), zap.String("time", t.Format("2006-01-02 15:04:05")))
-				err := job.JobFunc(ctx)
-				if err != nil {
-					log.Debug("JobFunc funished with error", zap.String("jobName", job.Name), zap.String("time", time.Now().Format("2006-01-02 15:04:05")), zap.Error(err))
-				} else {
-					log.Debug("JobFunc funished successfuly", zap.String("jobName", job.Name), zap.String("time", time.Now().Format("2006-01-02 15:04:05")))
-				}
-			}
+	schedule := job.Schedule
+	if schedule == nil {
+		if job.Period <= 0 {
+			return fmt.Errorf("job has no schedule: %v", job.Name)
 		}
-	}(ctx)
+		schedule = Every(job.Period)
+	}
 
+	job.runsCtx, job.cancelRuns = context.WithCancel(ctx)
+	ctx, job.cancel = context.WithCancel(ctx)
+	go job.schedule(ctx, job.runsCtx, schedule)
 	return nil
 }
 
+// Stop останавливает расписание задачи, не дожидаясь выполняющихся запусков
 func (job *Job) Stop() error {
-	if job.ticker == nil {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+
+	if job.cancel == nil {
 		return fmt.Errorf("job is not running: %v", job.Name)
 	}
+	job.cancel()
+	job.cancel = nil
+	return nil
+}
 
-	job.ticker.Stop()
-	job.ticker = nil
+// Shutdown останавливает расписание задачи и ожидает завершения выполняющихся запусков, запуски из очереди OverlapQueue отменяются
+// Если ctx завершится раньше, то контекст запусков отменяется и возвращается ошибка ctx
+func (job *Job) Shutdown(ctx context.Context) error {
+	job.lock.Lock()
+	if job.cancel != nil {
+		job.cancel()
+		job.cancel = nil
+	}
+	job.queued = false
+	cancelRuns := job.cancelRuns
+	job.lock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		job.runs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		if cancelRuns != nil {
+			cancelRuns()
+		}
+		return ctx.Err()
+	}
+}
+
+func (job *Job) schedule(ctx, runsCtx context.Context, schedule Schedule) {
+	if leader, ok := job.Leader.(ReadyLeader); ok {
+		select {
+		case <-ctx.Done():
+			return
+		case <-leader.Ready():
+		}
+	}
+	next := time.Now()
+	if !job.RunOnStart {
+		next = schedule.Next(next)
+	}
+	if next.IsZero() {
+		log.Warn(ctx, "Job schedule has no next run", zap.String("jobName", job.Name))
+		return
+	}
+	timer := time.NewTimer(time.Until(next) + job.jitter())
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			if job.Leader != nil && !job.Leader.IsLeader() {
+				log.Debug(ctx, "Skipping singleton job, replica is not leader", zap.String("jobName", job.Name))
+				break
+			}
+			job.dispatch(runsCtx)
+		}
+
+		// следующий запуск считается от запланированного времени, как у time.Ticker,
+		// запуски, пропущенные за время выполнения задачи, не накапливаются
+		now := time.Now()
+		next = schedule.Next(next)
+		for !next.IsZero() && next.Before(now) {
+			next = schedule.Next(next)
+		}
+		if next.IsZero() {
+			log.Warn(ctx, "Job schedule has no next run, job stopped", zap.String("jobName", job.Name))
+			return
+		}
+		timer.Reset(time.Until(next) + job.jitter())
+	}
+}
+
+// dispatch запускает задачу с учетом Overlap, возвращает false, если запуск пропущен
+func (job *Job) dispatch(ctx context.Context) bool {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+
+	if job.cancel == nil { // задача остановлена, Shutdown может уже ожидать запуски
+		return false
+	}
+	if job.running > 0 {
+		switch job.Overlap {
+		case OverlapSkip:
+			log.Debug(ctx, "Skipping job, previous run is in progress", zap.String("jobName", job.Name))
+			return false
+		case OverlapQueue:
+			job.queued = true
+			return true
+		}
+	}
+	job.running++
+	RunningGauge.WithLabelValues(job.Name).Set(float64(job.running))
+	job.runs.Add(1)
+	go job.run(ctx)
+	return true
+}
+
+// run выполняет запуск и запуски, поставленные в очередь за ним
+func (job *Job) run(ctx context.Context) {
+	defer job.runs.Done()
+	ctx = log.With(ctx, zap.String("jobName", job.Name))
+	logger := log.FromContext(ctx)
+	for {
+		logger.Debug("Running job", zap.String("time", time.Now().Format("2006-01-02 15:04:05")))
+		start := job.started()
+		err := job.execute(ctx)
+		job.finished(start, err)
+		if err != nil && ctx.Err() == nil {
+			logger.Error("JobFunc funished with error", zap.String("time", time.Now().Format("2006-01-02 15:04:05")), zap.Error(err))
+		} else if err == nil {
+			logger.Debug("JobFunc funished successfuly", zap.String("time", time.Now().Format("2006-01-02 15:04:05")))
+		}
+
+		job.lock.Lock()
+		if job.queued && ctx.Err() == nil {
+			job.queued = false
+			job.lock.Unlock()
+			continue
+		}
+		job.running--
+		RunningGauge.WithLabelValues(job.Name).Set(float64(job.running))
+		job.lock.Unlock()
+		return
+	}
+}
+
+// Trigger запускает задачу вне расписания с учетом Overlap, например по запросу администратора
+// Задача должна быть запущена через Run, задача с Leader запускается только на реплике-лидере,
+// иначе возвращается ErrNotLeader, чтобы запуск не дублировал работу лидера
+func (job *Job) Trigger() error {
+	if job.Leader != nil && !job.Leader.IsLeader() {
+		return fmt.Errorf("job %v: %w", job.Name, ErrNotLeader)
+	}
+	job.lock.Lock()
+	running, ctx := job.cancel != nil, job.runsCtx
+	job.lock.Unlock()
+	if !running {
+		return fmt.Errorf("job is not running: %v", job.Name)
+	}
+	if !job.dispatch(ctx) {
+		return fmt.Errorf("job run is skipped: %v", job.Name)
+	}
 	return nil
 }
+
+// execute выполняет задачу с повторами по Retry, каждая попытка ограничена Timeout
+func (job *Job) execute(ctx context.Context) error {
+	for attempt := 1; ; attempt++ {
+		err := job.attempt(ctx)
+		if err == nil || IsPermanent(err) || attempt >= job.Retry.MaxAttempts || ctx.Err() != nil {
+			return err
+		}
+		backoff := job.Retry.backoff(attempt)
+		log.Warn(ctx, "JobFunc failed, retrying", zap.Int("attempt", attempt), zap.Duration("backoff", backoff), zap.Error(err))
+		if !sleep(ctx, backoff) {
+			return err
+		}
+	}
+}
+
+func (job *Job) attempt(ctx context.Context) error {
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+	return job.JobFunc(ctx)
+}
+
+func (job *Job) jitter() time.Duration {
+	if job.Jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(job.Jitter)))
+}
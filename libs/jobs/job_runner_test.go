@@ -0,0 +1,141 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	log "route256/libs/logger"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testLeader struct {
+	leader atomic.Bool
+}
+
+func (l *testLeader) IsLeader() bool {
+	return l.leader.Load()
+}
+
+func TestSingletonJob(t *testing.T) {
+	log.Init(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var runs atomic.Int64
+	leader := &testLeader{}
+	job := NewJob("singleton", func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}, 10*time.Millisecond)
+	job.Leader = leader
+	job.RunOnStart = true
+	require.NoError(t, job.Run(ctx))
+
+	time.Sleep(55 * time.Millisecond)
+	require.Zero(t, runs.Load()) // not leader
+
+	leader.leader.Store(true)
+	time.Sleep(55 * time.Millisecond)
+	require.NoError(t, job.Shutdown(ctx))
+	require.GreaterOrEqual(t, runs.Load(), int64(3))
+}
+
+type testReadyLeader struct {
+	testLeader
+	ready chan struct{}
+}
+
+func (l *testReadyLeader) Ready() <-chan struct{} {
+	return l.ready
+}
+
+func TestSingletonJobWaitsElection(t *testing.T) {
+	log.Init(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var runs atomic.Int64
+	leader := &testReadyLeader{ready: make(chan struct{})}
+	job := NewJob("singleton_on_start", func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}, time.Hour)
+	job.Leader = leader
+	job.RunOnStart = true
+	require.NoError(t, job.Run(ctx))
+
+	time.Sleep(20 * time.Millisecond)
+	require.Zero(t, runs.Load()) // election is not finished
+	leader.leader.Store(true)
+	close(leader.ready)
+	require.Eventually(t, func() bool {
+		return runs.Load() == 1
+	}, time.Second, 5*time.Millisecond)
+	require.NoError(t, job.Shutdown(ctx))
+}
+
+func TestJobRetry(t *testing.T) {
+	log.Init(true)
+	ctx := context.Background()
+	errTemporary := errors.New("temporary")
+
+	tests := []struct {
+		name     string
+		failures int
+		err      error
+		attempts int
+		wantErr  bool
+	}{
+		{"success", 0, errTemporary, 1, false},
+		{"retried until success", 2, errTemporary, 3, false},
+		{"max attempts", 10, errTemporary, 4, true},
+		{"permanent error", 10, Permanent(errTemporary), 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			job := NewJob("retry", func(ctx context.Context) error {
+				attempts++
+				if attempts <= tt.failures {
+					return tt.err
+				}
+				return nil
+			}, time.Hour)
+			job.Retry = RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+			err := job.execute(ctx)
+			if tt.wantErr {
+				require.ErrorIs(t, err, errTemporary)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.attempts, attempts)
+		})
+	}
+}
+
+func TestJobTimeout(t *testing.T) {
+	job := NewJob("timeout", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, time.Hour)
+	job.Timeout = 10 * time.Millisecond
+	require.ErrorIs(t, job.execute(context.Background()), context.DeadlineExceeded)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 3}
+	require.Equal(t, 100*time.Millisecond, p.backoff(1))
+	require.Equal(t, 300*time.Millisecond, p.backoff(2))
+	require.Equal(t, 900*time.Millisecond, p.backoff(3))
+	require.Equal(t, time.Second, p.backoff(10))
+
+	p.Jitter = 0.5
+	for i := 0; i < 10; i++ {
+		require.InDelta(t, float64(100*time.Millisecond), float64(p.backoff(1)), float64(50*time.Millisecond))
+	}
+}
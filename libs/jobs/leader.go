@@ -0,0 +1,159 @@
+package jobs
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "route256/libs/logger"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Выбор лидера среди реплик сервиса для singleton-задач, которые должны выполняться только на одной реплике.
+// PostgresLeader держит сессионную advisory блокировку Postgres на отдельном соединении из пула:
+// реплика, получившая блокировку, становится лидером, остальные периодически пытаются ее получить.
+// Если лидер завершается или теряет соединение с базой, Postgres снимает блокировку вместе с сессией
+// и лидером становится реплика, которая первой получит блокировку при следующей попытке.
+// Лидер проверяет соединение с тем же интервалом, так что две реплики могут считать себя лидерами не дольше интервала,
+// задачи, которым это критично, должны быть идемпотентными.
+
+const defaultLeaderInterval = 5 * time.Second
+
+type Leader interface {
+	IsLeader() bool
+}
+
+// ReadyLeader лидер, которому нужно время на первые выборы, задача не запускается до их завершения,
+// иначе запуск при старте сервиса (RunOnStart) пропускается на всех репликах
+type ReadyLeader interface {
+	Leader
+	Ready() <-chan struct{} // Закрывается после первой попытки получить блокировку
+}
+
+type PostgresLeader struct {
+	pool     *pgxpool.Pool
+	name     string
+	lockID   int64
+	interval time.Duration
+	leader   atomic.Bool
+	cancel   context.CancelFunc
+	done     sync.WaitGroup
+	ready    chan struct{}
+}
+
+// NewPostgresLeader запускает выбор лидера для группы реплик name, ID блокировки вычисляется по name
+// Проверка и попытки получить блокировку выполняются каждые interval, если 0, то 5s
+// Выбор лидера останавливается при завершении ctx или Close, Close надо вызвать до закрытия pool,
+// так как pool.Close ожидает возврата соединения с блокировкой
+func NewPostgresLeader(ctx context.Context, pool *pgxpool.Pool, name string, interval time.Duration) *PostgresLeader {
+	if interval <= 0 {
+		interval = defaultLeaderInterval
+	}
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(name))
+	ctx, cancel := context.WithCancel(ctx)
+	l := &PostgresLeader{
+		pool:     pool,
+		name:     name,
+		lockID:   int64(hash.Sum64()),
+		interval: interval,
+		cancel:   cancel,
+		ready:    make(chan struct{}),
+	}
+	l.done.Add(1)
+	go l.run(ctx)
+	return l
+}
+
+// IsLeader возвращает true, если реплика сейчас держит блокировку
+func (l *PostgresLeader) IsLeader() bool {
+	return l.leader.Load()
+}
+
+// Ready возвращает канал, который закрывается после первой попытки получить блокировку
+func (l *PostgresLeader) Ready() <-chan struct{} {
+	return l.ready
+}
+
+// Close снимает блокировку и останавливает выбор лидера
+func (l *PostgresLeader) Close() {
+	l.cancel()
+	l.done.Wait()
+}
+
+func (l *PostgresLeader) run(ctx context.Context) {
+	defer l.done.Done()
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	var conn *pgxpool.Conn
+	for first := true; ; first = false {
+		var err error
+		if conn == nil {
+			conn, err = l.tryLock(ctx)
+		} else {
+			err = conn.Conn().Ping(ctx)
+			if err != nil {
+				_ = conn.Conn().Close(context.Background()) // соединение неисправно, блокировка снимется вместе с сессией
+				conn.Release()
+				conn = nil
+			}
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Warn(ctx, "leader election error", zap.String("name", l.name), zap.Error(err))
+		}
+		l.setLeader(ctx, conn != nil)
+		if first {
+			close(l.ready)
+		}
+
+		select {
+		case <-ctx.Done():
+			if conn != nil {
+				l.unlock(conn)
+			}
+			l.setLeader(ctx, false)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tryLock пытается получить блокировку, возвращает соединение, на котором она получена, или nil
+func (l *PostgresLeader) tryLock(ctx context.Context) (*pgxpool.Conn, error) {
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "acquiring connection for leader lock")
+	}
+	var locked bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", l.lockID).Scan(&locked); err != nil {
+		conn.Release()
+		return nil, errors.Wrap(err, "taking leader lock")
+	}
+	if !locked {
+		conn.Release()
+		return nil, nil
+	}
+	return conn, nil
+}
+
+func (l *PostgresLeader) unlock(conn *pgxpool.Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), l.interval)
+	defer cancel()
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", l.lockID); err != nil {
+		log.Warn(ctx, "releasing leader lock", zap.String("name", l.name), zap.Error(err))
+		_ = conn.Conn().Close(ctx) // блокировка снимется вместе с сессией
+	}
+	conn.Release()
+}
+
+func (l *PostgresLeader) setLeader(ctx context.Context, leader bool) {
+	if l.leader.Swap(leader) != leader {
+		log.Info(ctx, "leadership changed", zap.String("name", l.name), zap.Bool("leader", leader))
+	}
+}
@@ -0,0 +1,119 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	log "route256/libs/logger"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Manager управляет набором задач сервиса: запускает их вместе и останавливает при graceful shutdown,
+// дожидаясь выполняющихся запусков.
+
+type Manager struct {
+	lock    sync.Mutex
+	jobs    []*Job
+	names   map[string]*Job
+	ctx     context.Context
+	started bool
+}
+
+func NewManager() *Manager {
+	return &Manager{
+		names: make(map[string]*Job),
+	}
+}
+
+// Add регистрирует задачи, имена задач должны быть уникальными
+// Задачи, добавленные после Start, запускаются сразу
+func (m *Manager) Add(jobs ...*Job) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, job := range jobs {
+		if _, ok := m.names[job.Name]; ok {
+			return fmt.Errorf("job is already registered: %v", job.Name)
+		}
+		if m.started {
+			if err := job.Run(m.ctx); err != nil {
+				return err
+			}
+		}
+		m.names[job.Name] = job
+		m.jobs = append(m.jobs, job)
+	}
+	return nil
+}
+
+// Start запускает все зарегистрированные задачи, задачи работают до завершения ctx или Shutdown
+func (m *Manager) Start(ctx context.Context) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.started {
+		return errors.New("jobs are already started")
+	}
+	var result error
+	for _, job := range m.jobs {
+		if err := job.Run(ctx); err != nil {
+			result = multiError(result, errors.WithMessagef(err, "error starting job %v", job.Name))
+		}
+	}
+	m.ctx = ctx
+	m.started = true
+	return result
+}
+
+// Shutdown останавливает расписания всех задач и ожидает завершения выполняющихся запусков, пока не завершится ctx
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.lock.Lock()
+	jobs := append([]*Job(nil), m.jobs...)
+	m.started = false
+	m.lock.Unlock()
+
+	var wg sync.WaitGroup
+	var resultLock sync.Mutex
+	var result error
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(job *Job) {
+			defer wg.Done()
+			if err := job.Shutdown(ctx); err != nil {
+				log.Warn(ctx, "job is not finished before shutdown", zap.String("jobName", job.Name), zap.Error(err))
+				resultLock.Lock()
+				result = multiError(result, errors.WithMessagef(err, "shutting down job %v", job.Name))
+				resultLock.Unlock()
+			}
+		}(job)
+	}
+	wg.Wait()
+	return result
+}
+
+// Jobs возвращает зарегистрированные задачи в порядке добавления
+func (m *Manager) Jobs() []*Job {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return append([]*Job(nil), m.jobs...)
+}
+
+// Job возвращает задачу по имени
+func (m *Manager) Job(name string) (*Job, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	job, ok := m.names[name]
+	return job, ok
+}
+
+func multiError(result, err error) error {
+	if result == nil {
+		return err
+	}
+	return errors.WithMessage(result, err.Error())
+}
@@ -0,0 +1,94 @@
+package jobs
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	log "route256/libs/logger"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverlapPolicy(t *testing.T) {
+	log.Init(true)
+	tests := []struct {
+		name          string
+		overlap       OverlapPolicy
+		runs          int64
+		maxConcurrent int64
+	}{
+		{"skip", OverlapSkip, 1, 1},
+		{"queue", OverlapQueue, 2, 1},
+		{"concurrent", OverlapConcurrent, 3, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			var runs, running, maxConcurrent atomic.Int64
+			release := make(chan struct{})
+			job := NewJob("overlap", func(ctx context.Context) error {
+				runs.Add(1)
+				cur := running.Add(1)
+				for prev := maxConcurrent.Load(); cur > prev && !maxConcurrent.CompareAndSwap(prev, cur); prev = maxConcurrent.Load() {
+				}
+				defer running.Add(-1)
+				<-release
+				return nil
+			}, time.Hour)
+			job.Overlap = tt.overlap
+			require.NoError(t, job.Run(ctx))
+
+			for i := 0; i < 3; i++ { // second and third runs start while first is in progress
+				job.dispatch(ctx)
+			}
+			time.Sleep(10 * time.Millisecond)
+			close(release)
+			time.Sleep(10 * time.Millisecond) // queued run starts after first one, shutdown drops runs which are still queued
+			require.NoError(t, job.Shutdown(ctx))
+			require.Equal(t, tt.runs, runs.Load())
+			require.Equal(t, tt.maxConcurrent, maxConcurrent.Load())
+		})
+	}
+}
+
+func TestManager(t *testing.T) {
+	log.Init(true)
+	ctx := context.Background()
+	m := NewManager()
+
+	var finished atomic.Bool
+	started := make(chan struct{})
+	slow := NewJob("slow", func(ctx context.Context) error {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		finished.Store(true)
+		return nil
+	}, time.Hour)
+	slow.RunOnStart = true
+	stuck := NewJob("stuck", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, time.Hour)
+
+	require.NoError(t, m.Add(slow))
+	require.Error(t, m.Add(NewJob("slow", nil, time.Hour)))
+	require.NoError(t, m.Start(ctx))
+	require.Error(t, m.Start(ctx))
+	<-started
+	require.NoError(t, m.Shutdown(ctx))
+	require.True(t, finished.Load()) // shutdown waits for running job
+
+	m = NewManager()
+	stuck.RunOnStart = true
+	require.NoError(t, m.Add(stuck))
+	require.NoError(t, m.Start(ctx))
+	time.Sleep(10 * time.Millisecond)
+	shutdownCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, m.Shutdown(shutdownCtx), context.DeadlineExceeded) // running job is cancelled
+	_, ok := m.Job("stuck")
+	require.True(t, ok)
+	require.Len(t, m.Jobs(), 1)
+}
@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// Повторы задачи при ошибке.
+// Если JobFunc возвращает ошибку, задача повторяется по RetryPolicy с экспоненциальной задержкой, не дожидаясь следующего запуска,
+// пока не будет сделано MaxAttempts попыток. Ошибку, которую повторять бессмысленно, задача помечает через Permanent.
+// Каждая попытка получает собственный таймаут Job.Timeout через производный контекст.
+
+const (
+	defaultInitialBackoff = 100 * time.Millisecond
+	defaultMultiplier     = 2
+)
+
+type RetryPolicy struct {
+	MaxAttempts    int           // Максимальное количество попыток, включая первую, 0 или 1 - без повторов
+	InitialBackoff time.Duration // Задержка перед первым повтором, если 0, то 100ms
+	MaxBackoff     time.Duration // Максимальная задержка, если 0, то не ограничена
+	Multiplier     float64       // Множитель задержки для следующего повтора, если 0, то 2
+	Jitter         float64       // Доля случайного отклонения задержки, от 0 до 1, например 0.2 - плюс-минус 20%
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent помечает ошибку задачи как неповторяемую, задача не повторяется до следующего запуска по расписанию
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// IsPermanent проверяет, что ошибка помечена через Permanent
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
+
+// backoff возвращает задержку перед повтором после attempt попыток
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	if backoff <= 0 {
+		backoff = defaultInitialBackoff
+	}
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = defaultMultiplier
+	}
+	delay := float64(backoff)
+	for i := 1; i < attempt; i++ {
+		delay *= multiplier
+		if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// sleep ожидает d или завершения ctx, возвращает false, если ctx завершен
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
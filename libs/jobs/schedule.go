@@ -0,0 +1,197 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Расписания запуска задач.
+// Every запускает задачу с фиксированным периодом, ParseCron разбирает cron выражение из пяти полей:
+// минуты (0-59), часы (0-23), день месяца (1-31), месяц (1-12), день недели (0-6, 0 и 7 - воскресенье).
+// В полях поддерживаются *, списки через запятую, диапазоны через дефис и шаг через /, например "*/15 9-18 * * 1-5".
+// Если ограничены и день месяца, и день недели, то задача запускается в дни, подходящие под любое из них, как в cron.
+// Также поддерживаются сокращения @yearly (@annually), @monthly, @weekly, @daily (@midnight), @hourly и @every <duration>.
+// Время расписания вычисляется в часовом поясе переданного времени, для задач это локальное время сервиса.
+
+type Schedule interface {
+	Next(t time.Time) time.Time // Возвращает время следующего запуска после t
+}
+
+type periodSchedule struct {
+	period time.Duration
+}
+
+// Every возвращает расписание с запуском каждые period
+func Every(period time.Duration) Schedule {
+	return periodSchedule{period: period}
+}
+
+func (s periodSchedule) Next(t time.Time) time.Time {
+	return t.Add(s.period)
+}
+
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64 // Битовые маски допустимых значений полей
+	domAny, dowAny                bool   // Поле не ограничено (*)
+}
+
+type cronField struct {
+	min, max int
+}
+
+var cronFields = []cronField{
+	{0, 59}, // минута
+	{0, 23}, // час
+	{1, 31}, // день месяца
+	{1, 12}, // месяц
+	{0, 7},  // день недели, 7 - воскресенье
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// maxCronSearch ограничивает поиск следующего запуска для выражений, которые никогда не срабатывают, например "0 0 30 2 *"
+const maxCronSearch = 5 * 366 * 24 * time.Hour
+
+// ParseCron разбирает cron выражение, например "0 3 * * *" - каждый день в 03:00
+func ParseCron(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		period, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("parsing cron %q: %w", spec, err)
+		}
+		if period <= 0 {
+			return nil, fmt.Errorf("parsing cron %q: period must be positive", spec)
+		}
+		return Every(period), nil
+	}
+	if expanded, ok := cronDescriptors[spec]; ok {
+		spec = expanded
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("parsing cron %q: expected %d fields, got %d", spec, len(cronFields), len(parts))
+	}
+	masks := make([]uint64, len(parts))
+	for i, part := range parts {
+		mask, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("parsing cron %q: %w", spec, err)
+		}
+		masks[i] = mask
+	}
+	dow := masks[4]
+	if dow&(1<<7) != 0 {
+		dow |= 1
+	}
+	return &cronSchedule{
+		minute: masks[0],
+		hour:   masks[1],
+		dom:    masks[2],
+		month:  masks[3],
+		dow:    dow,
+		domAny: strings.HasPrefix(parts[2], "*"),
+		dowAny: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+// MustParseCron разбирает cron выражение и паникует при ошибке, для выражений, заданных в коде
+func MustParseCron(spec string) Schedule {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		panic(err)
+	}
+	return schedule
+}
+
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	var mask uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			var err error
+			rangePart = item[:i]
+			step, err = strconv.Atoi(item[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", item)
+			}
+		}
+
+		from, to := bounds.min, bounds.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid range in %q", item)
+			}
+			if to, err = strconv.Atoi(bounds[1]); err != nil {
+				return 0, fmt.Errorf("invalid range in %q", item)
+			}
+		default:
+			value, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", item)
+			}
+			from, to = value, value
+			if step > 1 { // "5/15" означает с 5 до конца диапазона с шагом 15
+				to = bounds.max
+			}
+		}
+		if from < bounds.min || to > bounds.max || from > to {
+			return 0, fmt.Errorf("value out of range %d-%d in %q", bounds.min, bounds.max, item)
+		}
+		for v := from; v <= to; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+// Next перебирает время от t с точностью до минуты, пропуская целиком неподходящие месяцы, дни и часы
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxCronSearch)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron(t *testing.T) {
+	at := func(s string) time.Time {
+		result, err := time.ParseInLocation("2006-01-02 15:04", s, time.UTC)
+		require.NoError(t, err)
+		return result
+	}
+	tests := []struct {
+		name string
+		spec string
+		from string
+		next string
+	}{
+		{"daily at 03:00 today", "0 3 * * *", "2023-05-20 01:30", "2023-05-20 03:00"},
+		{"daily at 03:00 tomorrow", "0 3 * * *", "2023-05-20 03:00", "2023-05-21 03:00"},
+		{"every 15 minutes", "*/15 * * * *", "2023-05-20 10:07", "2023-05-20 10:15"},
+		{"working hours on weekdays", "30 9-18 * * 1-5", "2023-05-19 18:45", "2023-05-22 09:30"},
+		{"list and step from value", "5/20 0,12 * * *", "2023-05-20 00:46", "2023-05-20 12:05"},
+		{"month end of february", "0 0 28-31 2 *", "2023-02-28 00:00", "2024-02-28 00:00"},
+		{"day of month or sunday", "0 0 1 * 7", "2023-05-20 12:00", "2023-05-21 00:00"},
+		{"descriptor", "@monthly", "2023-05-20 12:00", "2023-06-01 00:00"},
+		{"every duration", "@every 90m", "2023-05-20 12:00", "2023-05-20 13:30"},
+		{"never", "0 0 30 2 *", "2023-05-20 12:00", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCron(tt.spec)
+			require.NoError(t, err)
+			next := schedule.Next(at(tt.from))
+			if tt.next == "" {
+				require.True(t, next.IsZero())
+				return
+			}
+			require.Equal(t, at(tt.next), next)
+		})
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-1 * * * *", "@every -1s"} {
+		_, err := ParseCron(spec)
+		require.Error(t, err, spec)
+	}
+}
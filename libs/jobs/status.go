@@ -0,0 +1,109 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Статус задачи: результаты последнего запуска и счетчики запусков.
+// Те же данные публикуются в метриках Prometheus с меткой job, статусы всех задач Manager отдает AdminHandler (admin.go).
+
+type Status struct {
+	Name         string        `json:"name"`
+	Running      int           `json:"running"`       // Количество выполняющихся запусков
+	LastStart    time.Time     `json:"last_start"`    // Время начала последнего запуска
+	LastDuration time.Duration `json:"last_duration"` // Длительность последнего завершенного запуска, включая повторы
+	LastError    string        `json:"last_error"`    // Ошибка последнего завершенного запуска, пустая при успехе
+	Runs         uint64        `json:"runs"`          // Количество завершенных запусков
+	Failures     uint64        `json:"failures"`      // Количество запусков, завершенных с ошибкой
+}
+
+var (
+	RunsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "route256",
+		Subsystem: "jobs",
+		Name:      "runs_total",
+	},
+		[]string{"job"},
+	)
+	FailuresCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "route256",
+		Subsystem: "jobs",
+		Name:      "failures_total",
+	},
+		[]string{"job"},
+	)
+	RunningGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "route256",
+		Subsystem: "jobs",
+		Name:      "running",
+	},
+		[]string{"job"},
+	)
+	LastStartGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "route256",
+		Subsystem: "jobs",
+		Name:      "last_start_timestamp_seconds",
+	},
+		[]string{"job"},
+	)
+	LastSuccessGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "route256",
+		Subsystem: "jobs",
+		Name:      "last_success_timestamp_seconds",
+	},
+		[]string{"job"},
+	)
+	HistogramDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "route256",
+		Subsystem: "jobs",
+		Name:      "histogram_duration_seconds",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 18),
+	},
+		[]string{"job", "status"},
+	)
+)
+
+// Status возвращает статус задачи
+func (job *Job) Status() Status {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+
+	status := job.status
+	status.Name = job.Name
+	status.Running = job.running
+	return status
+}
+
+func (job *Job) started() time.Time {
+	start := time.Now()
+	job.lock.Lock()
+	job.status.LastStart = start
+	job.lock.Unlock()
+	LastStartGauge.WithLabelValues(job.Name).Set(float64(start.Unix()))
+	return start
+}
+
+func (job *Job) finished(start time.Time, err error) {
+	duration := time.Since(start)
+	job.lock.Lock()
+	job.status.LastDuration = duration
+	job.status.Runs++
+	job.status.LastError = ""
+	if err != nil {
+		job.status.Failures++
+		job.status.LastError = err.Error()
+	}
+	job.lock.Unlock()
+
+	RunsCounter.WithLabelValues(job.Name).Inc()
+	if err != nil {
+		FailuresCounter.WithLabelValues(job.Name).Inc()
+		HistogramDuration.WithLabelValues(job.Name, "error").Observe(duration.Seconds())
+		return
+	}
+	LastSuccessGauge.WithLabelValues(job.Name).Set(float64(time.Now().Unix()))
+	HistogramDuration.WithLabelValues(job.Name, "success").Observe(duration.Seconds())
+}
@@ -0,0 +1,136 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Адаптивный рейт лимитер для клиентов внешних сервисов по схеме AIMD (additive increase, multiplicative decrease).
+// Оборачивает другой RateLimiter и меняет его скорость по результатам запросов, которые передаются в Observe:
+// пока сервис отвечает без перегрузки, скорость растет на Increase раз в Interval,
+// при перегрузке (ошибка, для которой IsOverload возвращает true, или задержка ответа выше TargetLatency)
+// скорость умножается на Decrease, но не чаще раза в Interval, чтобы ответы на уже отправленные запросы не снижали ее повторно.
+// Скорость остается в пределах от MinRate до MaxRate, начальная скорость - MaxRate.
+
+const (
+	defaultAdaptiveInterval = time.Second
+	defaultAdaptiveDecrease = 0.5
+)
+
+type AdaptiveConfig struct {
+	MinRate       float64              // Нижняя граница скорости, событий в секунду, если 0, то 1
+	MaxRate       float64              // Верхняя граница и начальная скорость, событий в секунду
+	Increase      float64              // Прибавка к скорости за Interval без перегрузки, если 0, то 1
+	Decrease      float64              // Множитель скорости при перегрузке, от 0 до 1, если 0, то 0.5
+	Interval      time.Duration        // Минимальный интервал между изменениями скорости, если 0, то 1s
+	TargetLatency time.Duration        // Задержка ответа, выше которой сервис считается перегруженным, если 0, то не учитывается
+	IsOverload    func(err error) bool // Отбирает ошибки перегрузки сервиса, если nil, то перегрузка определяется только по задержке
+}
+
+type Adaptive struct {
+	limiter RateLimiter
+
+	lock         sync.Mutex
+	config       AdaptiveConfig
+	burst        int
+	rate         float64
+	lastChange   time.Time
+	lastDecrease time.Time
+}
+
+// NewAdaptive создает адаптивный лимитер поверх limiter, limiter сразу переключается на скорость config.MaxRate
+func NewAdaptive(limiter RateLimiter, burst int, config AdaptiveConfig) *Adaptive {
+	if config.MinRate <= 0 {
+		config.MinRate = 1
+	}
+	if config.MaxRate < config.MinRate {
+		config.MaxRate = config.MinRate
+	}
+	if config.Increase <= 0 {
+		config.Increase = 1
+	}
+	if config.Decrease <= 0 || config.Decrease >= 1 {
+		config.Decrease = defaultAdaptiveDecrease
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaultAdaptiveInterval
+	}
+	a := &Adaptive{
+		limiter:    limiter,
+		config:     config,
+		burst:      burst,
+		rate:       config.MaxRate,
+		lastChange: time.Now(),
+	}
+	limiter.SetLimit(a.rate, burst)
+	return a
+}
+
+// Wait ожидает своей очереди с текущей скоростью
+func (a *Adaptive) Wait(ctx context.Context) error {
+	return a.limiter.Wait(ctx)
+}
+
+// Allow пропускает событие, если его можно выполнить сразу с текущей скоростью
+func (a *Adaptive) Allow() bool {
+	return a.limiter.Allow()
+}
+
+// SetLimit меняет верхнюю границу скорости и burst, текущая скорость при необходимости снижается до новой границы
+func (a *Adaptive) SetLimit(rate float64, burst int) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.config.MaxRate = rate
+	if a.config.MaxRate < a.config.MinRate {
+		a.config.MaxRate = a.config.MinRate
+	}
+	a.burst = burst
+	if a.rate > a.config.MaxRate {
+		a.rate = a.config.MaxRate
+	}
+	a.limiter.SetLimit(a.rate, a.burst)
+}
+
+// Rate возвращает текущую скорость
+func (a *Adaptive) Rate() float64 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.rate
+}
+
+// Observe учитывает результат запроса: задержку ответа и ошибку
+// Ошибки, не относящиеся к перегрузке, например NotFound, считаются успешными ответами
+func (a *Adaptive) Observe(latency time.Duration, err error) {
+	overload := a.config.TargetLatency > 0 && latency > a.config.TargetLatency
+	if err != nil && a.config.IsOverload != nil && a.config.IsOverload(err) {
+		overload = true
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	now := time.Now()
+	rate := a.rate
+	switch {
+	case overload && now.Sub(a.lastDecrease) >= a.config.Interval:
+		rate *= a.config.Decrease
+		if rate < a.config.MinRate {
+			rate = a.config.MinRate
+		}
+		a.lastDecrease = now
+		a.lastChange = now
+	case !overload && now.Sub(a.lastChange) >= a.config.Interval:
+		rate += a.config.Increase
+		if rate > a.config.MaxRate {
+			rate = a.config.MaxRate
+		}
+		a.lastChange = now
+	}
+	if rate != a.rate {
+		a.rate = rate
+		a.limiter.SetLimit(rate, a.burst)
+	}
+}
@@ -0,0 +1,59 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+var errOverload = errors.New("overload")
+
+func TestAdaptive(t *testing.T) {
+	bucket := NewTokenBucket(1, 1)
+	a := NewAdaptive(bucket, 1, AdaptiveConfig{
+		MinRate:       2,
+		MaxRate:       10,
+		Increase:      3,
+		Decrease:      0.5,
+		Interval:      20 * time.Millisecond,
+		TargetLatency: 50 * time.Millisecond,
+		IsOverload: func(err error) bool {
+			return errors.Is(err, errOverload)
+		},
+	})
+	rate, _ := bucket.Limit()
+	require.Equal(t, 10.0, rate) // starts with upper bound
+
+	a.Observe(time.Millisecond, errOverload)
+	require.Equal(t, 5.0, a.Rate())
+	a.Observe(time.Millisecond, errOverload) // responses to requests sent before decrease are ignored
+	require.Equal(t, 5.0, a.Rate())
+
+	time.Sleep(25 * time.Millisecond)
+	a.Observe(100*time.Millisecond, nil) // high latency is overload too
+	require.Equal(t, 2.5, a.Rate())
+	time.Sleep(25 * time.Millisecond)
+	a.Observe(time.Millisecond, errOverload)
+	require.Equal(t, 2.0, a.Rate()) // not below MinRate
+	rate, _ = bucket.Limit()
+	require.Equal(t, 2.0, rate)
+
+	a.Observe(time.Millisecond, errors.New("not found")) // other errors are successful responses
+	require.Equal(t, 2.0, a.Rate())                      // increase waits for interval after decrease
+	time.Sleep(25 * time.Millisecond)
+	a.Observe(time.Millisecond, errors.New("not found"))
+	require.Equal(t, 5.0, a.Rate())
+	for i := 0; i < 3; i++ {
+		time.Sleep(25 * time.Millisecond)
+		a.Observe(time.Millisecond, nil)
+	}
+	require.Equal(t, 10.0, a.Rate()) // not above MaxRate
+
+	a.SetLimit(4, 2)
+	require.Equal(t, 4.0, a.Rate())
+	rate, burst := bucket.Limit()
+	require.Equal(t, 4.0, rate)
+	require.Equal(t, 2, burst)
+}
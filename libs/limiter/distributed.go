@@ -0,0 +1,216 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "route256/libs/logger"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Распределенный рейт лимитер: один бюджет событий на ключ для всех экземпляров сервиса.
+// Использует алгоритм GCRA (generic cell rate algorithm): для ключа в хранилище лежит только теоретическое время
+// прихода следующего события (TAT), каждое событие сдвигает его на интервал 1/rate,
+// событие можно выполнять, когда до TAT осталось не больше burst интервалов.
+// Состояние хранится в Store, например в Postgres (см. postgres_store.go), операция резервирования атомарна в хранилище.
+// Если хранилище недоступно, лимитер переключается на локальный TokenBucket и не ждет хранилище на каждом событии:
+// хранилище проверяется одним запросом не чаще раза в секунду, после каждой неудачной проверки интервал удваивается до 30s.
+// Как только хранилище снова отвечает, лимитер возвращается к нему. Локальный лимитер ограничивает только свой экземпляр,
+// поэтому его скорость стоит выбирать как долю общего бюджета.
+
+const (
+	defaultStoreTimeout = 100 * time.Millisecond // Таймаут запроса к хранилищу, если не задан
+	defaultMaxWait      = time.Minute            // Максимальное ожидание в Wait, если у контекста нет дедлайна
+	minProbeInterval    = time.Second            // Интервал первой проверки недоступного хранилища
+	maxProbeInterval    = 30 * time.Second       // Максимальный интервал проверки недоступного хранилища
+)
+
+var errStoreUnavailable = errors.New("distributed rate limiter store is unavailable")
+
+// RateLimiter общий интерфейс рейт лимитеров: TokenBucket, Distributed, Adaptive
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+	Allow() bool
+	SetLimit(rate float64, burst int)
+}
+
+// Store хранилище состояния GCRA
+// Reserve резервирует событие для key и возвращает задержку, через которую его можно выполнять
+// Если задержка больше maxWait, то событие не резервируется и возвращается false
+type Store interface {
+	Reserve(ctx context.Context, key string, interval time.Duration, burst int, maxWait time.Duration) (time.Duration, bool, error)
+}
+
+type Distributed struct {
+	store        Store
+	key          string
+	fallback     *TokenBucket
+	storeTimeout time.Duration
+
+	lock  sync.RWMutex
+	rate  float64
+	burst int
+
+	healthLock    sync.Mutex
+	degraded      bool          // Хранилище недоступно, используется fallback
+	probeAt       time.Time     // Время следующей проверки недоступного хранилища
+	probeInterval time.Duration // Текущий интервал проверки недоступного хранилища
+	minProbe      time.Duration
+	maxProbe      time.Duration
+}
+
+// NewDistributed создает распределенный лимитер для ключа key, например токена доступа к внешнему сервису
+// В rate передается количество событий в секунду на все экземпляры, в burst - количество событий, которые могут пройти одновременно
+// В fallback передается локальный лимитер на случай недоступности хранилища, если nil, то создается TokenBucket с rate и burst
+// Запрос к хранилищу ограничен storeTimeout, если 0, то используется 100ms
+func NewDistributed(store Store, key string, rate float64, burst int, fallback *TokenBucket, storeTimeout time.Duration) *Distributed {
+	if burst < 1 {
+		burst = 1
+	}
+	if fallback == nil {
+		fallback = NewTokenBucket(rate, burst)
+	}
+	if storeTimeout <= 0 {
+		storeTimeout = defaultStoreTimeout
+	}
+	return &Distributed{
+		store:        store,
+		key:          key,
+		fallback:     fallback,
+		storeTimeout: storeTimeout,
+		rate:         rate,
+		burst:        burst,
+		minProbe:     minProbeInterval,
+		maxProbe:     maxProbeInterval,
+	}
+}
+
+// Wait ожидает своей очереди в общем бюджете, пока не завершится ctx
+// Если очередь не успеет подойти до дедлайна ctx, то ошибка возвращается сразу без ожидания
+func (d *Distributed) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	maxWait := defaultMaxWait
+	if deadline, ok := ctx.Deadline(); ok {
+		maxWait = time.Until(deadline)
+	}
+	delay, ok, err := d.reserve(ctx, maxWait)
+	if err != nil {
+		return d.fallback.Wait(ctx)
+	}
+	if !ok {
+		return errors.Wrapf(context.DeadlineExceeded, "waiting more than %s for distributed rate limit", maxWait)
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done(): // зарезервированное событие не возвращается в бюджет, GCRA хранит только время
+		return ctx.Err()
+	}
+}
+
+// Allow резервирует событие, если его можно выполнить сразу
+func (d *Distributed) Allow() bool {
+	delay, ok, err := d.reserve(context.Background(), 0)
+	if err != nil {
+		return d.fallback.Allow()
+	}
+	return ok && delay <= 0
+}
+
+// SetLimit меняет общий бюджет на лету, скорость локального лимитера меняется пропорционально
+func (d *Distributed) SetLimit(rate float64, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	fallbackRate, _ := d.fallback.Limit()
+	if d.rate > 0 {
+		fallbackRate *= rate / d.rate
+	} else {
+		fallbackRate = rate
+	}
+	d.fallback.SetLimit(fallbackRate, burst)
+	d.rate = rate
+	d.burst = burst
+}
+
+// reserve резервирует событие в хранилище, при ошибке хранилища переключает лимитер на fallback
+// Пока хранилище недоступно, reserve сразу возвращает ошибку, кроме редких проверочных запросов
+func (d *Distributed) reserve(ctx context.Context, maxWait time.Duration) (time.Duration, bool, error) {
+	d.lock.RLock()
+	rate, burst := d.rate, d.burst
+	d.lock.RUnlock()
+	if rate <= 0 {
+		return 0, false, nil
+	}
+	if !d.useStore() {
+		return 0, false, errStoreUnavailable
+	}
+
+	storeCtx, cancel := context.WithTimeout(ctx, d.storeTimeout)
+	defer cancel()
+	interval := time.Duration(float64(time.Second) / rate)
+	delay, ok, err := d.store.Reserve(storeCtx, d.key, interval, burst, maxWait)
+	if ctx.Err() == nil { // отмена запроса вызывающим не говорит о недоступности хранилища
+		d.storeResult(ctx, err)
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return delay, ok, nil
+}
+
+// useStore проверяет, можно ли обращаться к хранилищу
+// Если хранилище недоступно, то к нему пропускается только один проверочный запрос после истечения интервала проверки
+func (d *Distributed) useStore() bool {
+	d.healthLock.Lock()
+	defer d.healthLock.Unlock()
+
+	if !d.degraded {
+		return true
+	}
+	now := time.Now()
+	if now.Before(d.probeAt) {
+		return false
+	}
+	d.probeAt = now.Add(d.probeInterval) // остальные запросы не ждут хранилище, пока идет проверка
+	return true
+}
+
+// storeResult переключает лимитер на fallback при ошибке хранилища и обратно, когда хранилище отвечает
+func (d *Distributed) storeResult(ctx context.Context, err error) {
+	d.healthLock.Lock()
+	defer d.healthLock.Unlock()
+
+	if err == nil {
+		if d.degraded {
+			d.degraded = false
+			log.Info(ctx, "distributed rate limiter store is available again", zap.String("key", d.key))
+		}
+		return
+	}
+	if !d.degraded {
+		d.degraded = true
+		d.probeInterval = d.minProbe
+		log.Warn(ctx, "distributed rate limiter store is unavailable, using local limiter", zap.String("key", d.key), zap.Error(err))
+	} else {
+		d.probeInterval *= 2
+		if d.probeInterval > d.maxProbe {
+			d.probeInterval = d.maxProbe
+		}
+	}
+	d.probeAt = time.Now().Add(d.probeInterval)
+}
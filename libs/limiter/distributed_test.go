@@ -0,0 +1,150 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	log "route256/libs/logger"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type memStore struct { // in-memory GCRA with the same semantics as PostgresStore
+	lock  sync.Mutex
+	tat   map[string]time.Time
+	down  bool
+	calls int // Number of Reserve calls
+}
+
+func (s *memStore) Reserve(ctx context.Context, key string, interval time.Duration, burst int, maxWait time.Duration) (time.Duration, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.calls++
+	if s.down {
+		return 0, false, errors.New("store is down")
+	}
+	now := time.Now()
+	tat := s.tat[key]
+	if tat.Before(now) {
+		tat = now
+	}
+	delay := tat.Sub(now) - time.Duration(burst-1)*interval
+	if delay > maxWait {
+		return 0, false, nil
+	}
+	s.tat[key] = tat.Add(interval)
+	if delay < 0 {
+		delay = 0
+	}
+	return delay, true, nil
+}
+
+func (s *memStore) reserveCalls() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.calls
+}
+
+func (s *memStore) setDown(down bool) {
+	s.lock.Lock()
+	s.down = down
+	s.lock.Unlock()
+}
+
+func TestDistributedSharedBudget(t *testing.T) {
+	log.Init(true)
+	store := &memStore{tat: make(map[string]time.Time)}
+	a := NewDistributed(store, "token", 10, 2, nil, 0)
+	b := NewDistributed(store, "token", 10, 2, nil, 0)
+	other := NewDistributed(store, "other", 10, 2, nil, 0)
+
+	require.True(t, a.Allow())
+	require.True(t, b.Allow())
+	require.False(t, a.Allow()) // burst is shared by replicas
+	require.False(t, b.Allow())
+	require.True(t, other.Allow()) // budgets of different keys are independent
+
+	time.Sleep(110 * time.Millisecond) // one event per 100ms
+	require.True(t, b.Allow())
+	require.False(t, a.Allow())
+}
+
+func TestDistributedWait(t *testing.T) {
+	log.Init(true)
+	ctx := context.Background()
+	store := &memStore{tat: make(map[string]time.Time)}
+	a := NewDistributed(store, "token", 20, 1, nil, 0)
+	b := NewDistributed(store, "token", 20, 1, nil, 0)
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		require.NoError(t, a.Wait(ctx))
+		require.NoError(t, b.Wait(ctx))
+	}
+	elapsed := time.Since(start)
+	require.GreaterOrEqual(t, elapsed, 140*time.Millisecond) // first event at once, 3 more by 50ms
+	require.Less(t, elapsed, 500*time.Millisecond)
+
+	shortCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, a.Wait(shortCtx), context.DeadlineExceeded) // fails without reserving
+	time.Sleep(60 * time.Millisecond)
+	require.True(t, b.Allow())
+}
+
+func TestDistributedFallback(t *testing.T) {
+	log.Init(true)
+	store := &memStore{tat: make(map[string]time.Time)}
+	d := NewDistributed(store, "token", 10, 1, NewTokenBucket(10, 2), 0)
+	d.minProbe = 100 * time.Millisecond
+
+	require.True(t, d.Allow())
+	require.False(t, d.Allow())
+
+	store.setDown(true)
+	require.True(t, d.Allow()) // local bucket is used while store is down
+	require.True(t, d.Allow())
+	require.False(t, d.Allow())
+	require.NoError(t, d.Wait(context.Background()))
+
+	store.setDown(false)
+	time.Sleep(110 * time.Millisecond)
+	require.True(t, d.Allow()) // back to shared budget
+	require.False(t, d.Allow())
+}
+
+func TestDistributedDegradedSkipsStore(t *testing.T) {
+	log.Init(true)
+	store := &memStore{tat: make(map[string]time.Time)}
+	d := NewDistributed(store, "token", 1000, 1, nil, 0)
+	d.minProbe = 50 * time.Millisecond
+	d.maxProbe = 80 * time.Millisecond
+
+	store.setDown(true)
+	for i := 0; i < 10; i++ {
+		d.Allow()
+	}
+	require.Equal(t, 1, store.reserveCalls()) // store is not queried until probe interval passes
+
+	time.Sleep(60 * time.Millisecond)
+	d.Allow() // failed probe, next one after 80ms
+	d.Allow()
+	require.Equal(t, 2, store.reserveCalls())
+
+	store.setDown(false)
+	time.Sleep(60 * time.Millisecond)
+	d.Allow()
+	require.Equal(t, 2, store.reserveCalls()) // probe interval grows after failed probe
+
+	time.Sleep(30 * time.Millisecond)
+	require.True(t, d.Allow()) // successful probe returns to store
+	require.Equal(t, 3, store.reserveCalls())
+	time.Sleep(5 * time.Millisecond)
+	d.Allow()
+	require.Equal(t, 4, store.reserveCalls())
+}
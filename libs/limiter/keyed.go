@@ -0,0 +1,79 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Набор лимитеров TokenBucket по ключам, например по пользователю и методу API.
+// Лимитер для ключа создается при первом обращении, лимитеры, к которым не обращались дольше idleTTL,
+// удаляются фоновой горутиной до завершения ctx. Удаленный лимитер при следующем обращении создается заново с полной корзиной,
+// поэтому idleTTL должен быть больше времени, за которое корзина наполняется.
+
+type keyedBucket struct {
+	bucket   *TokenBucket
+	lastUsed time.Time
+}
+
+type Keyed[KeyT comparable] struct {
+	lock      sync.Mutex
+	buckets   map[KeyT]*keyedBucket
+	idleTTL   time.Duration
+	newBucket func(key KeyT) *TokenBucket
+}
+
+// NewKeyed создает набор лимитеров, newBucket создает лимитер для нового ключа
+// В ctx передается контекст используемый для остановки удаления неиспользуемых лимитеров при graceful shutdown
+func NewKeyed[KeyT comparable](ctx context.Context, idleTTL time.Duration, newBucket func(key KeyT) *TokenBucket) *Keyed[KeyT] {
+	k := &Keyed[KeyT]{
+		buckets:   make(map[KeyT]*keyedBucket),
+		idleTTL:   idleTTL,
+		newBucket: newBucket,
+	}
+	if idleTTL > 0 {
+		go k.evictIdle(ctx)
+	}
+	return k
+}
+
+// Get возвращает лимитер для ключа, создавая его при необходимости
+func (k *Keyed[KeyT]) Get(key KeyT) *TokenBucket {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	b, ok := k.buckets[key]
+	if !ok {
+		b = &keyedBucket{bucket: k.newBucket(key)}
+		k.buckets[key] = b
+	}
+	b.lastUsed = time.Now()
+	return b.bucket
+}
+
+// Len возвращает количество лимитеров
+func (k *Keyed[KeyT]) Len() int {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	return len(k.buckets)
+}
+
+func (k *Keyed[KeyT]) evictIdle(ctx context.Context) {
+	ticker := time.NewTicker(k.idleTTL / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			k.lock.Lock()
+			for key, b := range k.buckets {
+				if now.Sub(b.lastUsed) >= k.idleTTL {
+					delete(k.buckets, key)
+				}
+			}
+			k.lock.Unlock()
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	k := NewKeyed(ctx, 50*time.Millisecond, func(key string) *TokenBucket {
+		return NewTokenBucket(1, 1)
+	})
+
+	require.True(t, k.Get("a").Allow())
+	require.False(t, k.Get("a").Allow())
+	require.True(t, k.Get("b").Allow()) // keys have own limiters
+	require.Equal(t, 2, k.Len())
+
+	for i := 0; i < 4; i++ { // used limiter is kept
+		time.Sleep(20 * time.Millisecond)
+		k.Get("a")
+	}
+	require.Equal(t, 1, k.Len())
+	require.False(t, k.Get("a").Allow())
+
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, 0, k.Len())
+}
@@ -9,6 +9,8 @@ import (
 // Построен на базе time.Ticker.
 // Отправляет сообщения в канал Limiter.C не чаще установленного интервала.
 // В сообщении содержится время, когда оно отправлено в канал. По нему можно анализировать использование полосы пропускания.
+// Если нужен burst или ожидание с учетом контекста, используйте TokenBucket (token_bucket.go).
+// Если лимит должен быть общим для нескольких экземпляров сервиса, используйте Distributed (distributed.go).
 
 type Limiter struct {
 	C      chan time.Time
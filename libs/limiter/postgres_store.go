@@ -0,0 +1,53 @@
+package limiter
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+)
+
+// Хранилище состояния распределенного лимитера в Postgres.
+// Для каждого ключа хранится TAT в таблице rate_limits (см. миграцию сервиса), резервирование выполняется одним UPSERT,
+// поэтому конкурентные резервирования разных экземпляров сериализуются блокировкой строки.
+// Время берется из базы, так что расхождение часов экземпляров на лимит не влияет.
+//
+// CREATE TABLE rate_limits (key text PRIMARY KEY, tat timestamptz NOT NULL);
+
+// Интервалы передаются в микросекундах, новый TAT сдвигается на интервал только если задержка не превышает maxWait,
+// иначе строка не обновляется и запрос не возвращает строк
+const reserveQuery = `
+INSERT INTO rate_limits (key, tat) VALUES ($1, now() + $2::bigint * interval '1 microsecond')
+ON CONFLICT (key) DO UPDATE
+SET tat = greatest(rate_limits.tat, now()) + $2::bigint * interval '1 microsecond'
+WHERE greatest(rate_limits.tat, now()) - now() - ($3::bigint - 1) * $2::bigint * interval '1 microsecond' <= $4::bigint * interval '1 microsecond'
+RETURNING (extract(epoch FROM tat - now()) * 1000000)::bigint`
+
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresStore создает хранилище в базе pool, таблица rate_limits должна существовать
+func NewPostgresStore(pool *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{pool: pool}
+}
+
+// Reserve сдвигает TAT ключа на interval и возвращает задержку до события
+func (s *PostgresStore) Reserve(ctx context.Context, key string, interval time.Duration, burst int, maxWait time.Duration) (time.Duration, bool, error) {
+	var untilTAT int64
+	err := s.pool.QueryRow(ctx, reserveQuery, key, interval.Microseconds(), burst, maxWait.Microseconds()).Scan(&untilTAT)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, errors.Wrap(err, "reserving rate limit")
+	}
+	// событие можно выполнять за burst интервалов до нового TAT
+	delay := time.Duration(untilTAT)*time.Microsecond - time.Duration(burst)*interval
+	if delay < 0 {
+		delay = 0
+	}
+	return delay, true, nil
+}
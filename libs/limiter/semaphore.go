@@ -0,0 +1,156 @@
+package limiter
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Ограничитель параллельности на основе семафора с весами.
+// Семафор разделяется между запросами: например, все исходящие запросы к внешнему сервису из разных обработчиков
+// получают разрешение у одного семафора, и одновременно выполняется не больше size единиц работы.
+// Тяжелые операции могут занимать больше одной единицы через вес n.
+// Ожидающие обслуживаются в порядке очереди: легкие операции не обгоняют тяжелую, ожидающую освобождения нужного веса.
+// Занятый вес и количество ожидающих публикуются в метриках с меткой name.
+
+var (
+	ErrWeightExceeded = errors.New("weight exceeds semaphore size")
+)
+
+var (
+	InFlightGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "route256",
+		Subsystem: "limiter",
+		Name:      "in_flight",
+	},
+		[]string{"name"},
+	)
+	WaitingGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "route256",
+		Subsystem: "limiter",
+		Name:      "waiting",
+	},
+		[]string{"name"},
+	)
+)
+
+type semaphoreWaiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+type Semaphore struct {
+	lock     sync.Mutex
+	size     int64
+	cur      int64
+	waiters  list.List
+	inFlight prometheus.Gauge
+	waiting  prometheus.Gauge
+}
+
+// NewSemaphore создает семафор, через который одновременно проходит вес не больше size
+// В name передается имя семафора для метрик
+func NewSemaphore(name string, size int64) *Semaphore {
+	return &Semaphore{
+		size:     size,
+		inFlight: InFlightGauge.WithLabelValues(name),
+		waiting:  WaitingGauge.WithLabelValues(name),
+	}
+}
+
+// Acquire ожидает освобождения веса n, пока не завершится ctx
+// При ошибке вес не занимается, иначе его надо освободить через Release
+func (s *Semaphore) Acquire(ctx context.Context, n int64) error {
+	if n > s.size {
+		return errors.Wrapf(ErrWeightExceeded, "acquiring %d of %d", n, s.size)
+	}
+	s.lock.Lock()
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.inFlight.Set(float64(s.cur))
+		s.lock.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	elem := s.waiters.PushBack(semaphoreWaiter{n: n, ready: ready})
+	s.waiting.Set(float64(s.waiters.Len()))
+	s.lock.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		select {
+		case <-ready: // вес занят одновременно с завершением ctx
+			s.cur -= n
+			s.notifyWaiters()
+		default:
+			isFront := s.waiters.Front() == elem
+			s.waiters.Remove(elem)
+			if isFront && s.size > s.cur { // следующие ожидающие могли ждать только этого
+				s.notifyWaiters()
+			}
+		}
+		s.inFlight.Set(float64(s.cur))
+		s.waiting.Set(float64(s.waiters.Len()))
+		return ctx.Err()
+	}
+}
+
+// TryAcquire занимает вес n, если он свободен и нет ожидающих, не дожидаясь освобождения
+func (s *Semaphore) TryAcquire(n int64) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.size-s.cur < n || s.waiters.Len() > 0 {
+		return false
+	}
+	s.cur += n
+	s.inFlight.Set(float64(s.cur))
+	return true
+}
+
+// Release освобождает вес n, занятый через Acquire или TryAcquire
+func (s *Semaphore) Release(n int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.cur -= n
+	if s.cur < 0 {
+		panic("limiter: semaphore released more than held")
+	}
+	s.notifyWaiters()
+	s.inFlight.Set(float64(s.cur))
+	s.waiting.Set(float64(s.waiters.Len()))
+}
+
+// InFlight возвращает занятый вес
+func (s *Semaphore) InFlight() int64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.cur
+}
+
+// notifyWaiters передает свободный вес ожидающим в порядке очереди, вызывается под блокировкой
+func (s *Semaphore) notifyWaiters() {
+	for {
+		next := s.waiters.Front()
+		if next == nil {
+			return
+		}
+		w := next.Value.(semaphoreWaiter)
+		if s.size-s.cur < w.n {
+			return
+		}
+		s.cur += w.n
+		s.waiters.Remove(next)
+		close(w.ready)
+	}
+}
@@ -0,0 +1,67 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemaphore(t *testing.T) {
+	ctx := context.Background()
+	s := NewSemaphore("test", 3)
+
+	require.NoError(t, s.Acquire(ctx, 2))
+	require.True(t, s.TryAcquire(1))
+	require.False(t, s.TryAcquire(1))
+	require.Equal(t, int64(3), s.InFlight())
+	require.ErrorIs(t, s.Acquire(ctx, 4), ErrWeightExceeded)
+
+	shortCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, s.Acquire(shortCtx, 1), context.DeadlineExceeded)
+
+	heavy := make(chan struct{})
+	go func() {
+		require.NoError(t, s.Acquire(ctx, 3))
+		close(heavy)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	s.Release(2)
+	require.False(t, s.TryAcquire(1)) // light acquire doesn't overtake waiting heavy one
+	s.Release(1)
+	<-heavy
+	require.Equal(t, int64(3), s.InFlight())
+	s.Release(3)
+	require.Equal(t, int64(0), s.InFlight())
+}
+
+func TestSemaphoreConcurrency(t *testing.T) {
+	ctx := context.Background()
+	s := NewSemaphore("test_concurrency", 4)
+	var inFlight, maxInFlight int64
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(n int64) {
+			defer wg.Done()
+			require.NoError(t, s.Acquire(ctx, n))
+			defer s.Release(n)
+			cur := atomic.AddInt64(&inFlight, n)
+			for {
+				prev := atomic.LoadInt64(&maxInFlight)
+				if cur <= prev || atomic.CompareAndSwapInt64(&maxInFlight, prev, cur) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt64(&inFlight, -n)
+		}(int64(i%2 + 1))
+	}
+	wg.Wait()
+	require.LessOrEqual(t, maxInFlight, int64(4))
+	require.Equal(t, int64(0), s.InFlight())
+}
@@ -0,0 +1,201 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Рейт лимитер на основе алгоритма token bucket.
+// Корзина вмещает burst токенов и пополняется со скоростью rate токенов в секунду, каждое событие забирает один токен.
+// Пока лимитер не используется, токены накапливаются до burst, поэтому после простоя до burst событий проходят сразу,
+// а в среднем события пропускаются не чаще rate в секунду.
+// В отличие от Limiter не требует фоновой горутины: количество токенов вычисляется по времени при каждом обращении.
+
+var (
+	ErrLimitExceeded = errors.New("rate limit can't be satisfied")
+)
+
+type TokenBucket struct {
+	lock   sync.Mutex
+	rate   float64   // Скорость пополнения, токенов в секунду
+	burst  int       // Вместимость корзины
+	tokens float64   // Токенов в корзине на момент last, отрицательное значение - токены зарезервированы наперед
+	last   time.Time // Время последнего пересчета tokens
+}
+
+// Reservation резерв токена, полученный через Reserve
+// Событие можно выполнять через Delay после резервирования, если резерв не нужен, его надо вернуть через Cancel
+type Reservation struct {
+	bucket    *TokenBucket
+	ok        bool
+	timeToAct time.Time
+	cancelled bool
+}
+
+// NewTokenBucket создает лимитер с полной корзиной
+// В rate передается количество событий в секунду, в burst - количество событий, которые могут пройти одновременно
+// Если burst меньше 1, то используется 1
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Allow забирает токен, если он есть, и возвращает true, иначе возвращает false не дожидаясь токена
+func (b *TokenBucket) Allow() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.advance(time.Now())
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Reserve резервирует токен, время ожидания токена возвращает Reservation.Delay
+// Если rate не больше 0, то токен зарезервировать нельзя, Reservation.OK возвращает false
+func (b *TokenBucket) Reserve() *Reservation {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := time.Now()
+	b.advance(now)
+	if b.tokens < 1 && b.rate <= 0 {
+		return &Reservation{bucket: b}
+	}
+	b.tokens--
+	r := &Reservation{bucket: b, ok: true, timeToAct: now}
+	if b.tokens < 0 {
+		r.timeToAct = now.Add(durationFromTokens(-b.tokens, b.rate))
+	}
+	return r
+}
+
+// Wait ожидает токен, пока не завершится ctx
+// Если токен не успеет появиться до дедлайна ctx, то ошибка возвращается сразу без ожидания
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r := b.Reserve()
+	if !r.OK() {
+		return ErrLimitExceeded
+	}
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(r.timeToAct) {
+		r.Cancel()
+		return errors.Wrapf(context.DeadlineExceeded, "waiting %s for rate limit", delay)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// SetLimit меняет скорость и вместимость корзины на лету, накопленные токены сохраняются в пределах нового burst
+func (b *TokenBucket) SetLimit(rate float64, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.advance(time.Now())
+	b.rate = rate
+	b.burst = burst
+	if b.tokens > float64(burst) {
+		b.tokens = float64(burst)
+	}
+}
+
+// Limit возвращает текущие скорость и вместимость корзины
+func (b *TokenBucket) Limit() (float64, int) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.rate, b.burst
+}
+
+// Tokens возвращает количество токенов в корзине на текущий момент, отрицательное значение - токены зарезервированы наперед
+func (b *TokenBucket) Tokens() float64 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.advance(time.Now())
+	return b.tokens
+}
+
+// advance пополняет корзину токенами, накопленными с момента last
+func (b *TokenBucket) advance(now time.Time) {
+	elapsed := now.Sub(b.last)
+	if elapsed <= 0 {
+		return
+	}
+	b.last = now
+	if b.rate <= 0 {
+		return
+	}
+	b.tokens += elapsed.Seconds() * b.rate
+	if b.tokens > float64(b.burst) {
+		b.tokens = float64(b.burst)
+	}
+}
+
+func durationFromTokens(tokens, rate float64) time.Duration {
+	return time.Duration(tokens / rate * float64(time.Second))
+}
+
+// OK возвращает false, если токен нельзя зарезервировать при текущей скорости
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay возвращает время, через которое можно выполнять событие
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return 0
+	}
+	delay := time.Until(r.timeToAct)
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+// Cancel возвращает токен в корзину, если событие еще не наступило
+func (r *Reservation) Cancel() {
+	b := r.bucket
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := time.Now()
+	if !r.ok || r.cancelled || !now.Before(r.timeToAct) {
+		return
+	}
+	r.cancelled = true
+	b.advance(now)
+	b.tokens++
+	if b.tokens > float64(b.burst) {
+		b.tokens = float64(b.burst)
+	}
+}
@@ -0,0 +1,62 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucketBurst(t *testing.T) {
+	b := NewTokenBucket(10, 3)
+	for i := 0; i < 3; i++ {
+		require.True(t, b.Allow())
+	}
+	require.False(t, b.Allow())
+
+	time.Sleep(110 * time.Millisecond) // one token per 100ms
+	require.True(t, b.Allow())
+	require.False(t, b.Allow())
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	ctx := context.Background()
+	b := NewTokenBucket(20, 2)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		require.NoError(t, b.Wait(ctx))
+	}
+	elapsed := time.Since(start)
+	require.GreaterOrEqual(t, elapsed, 90*time.Millisecond) // 2 tokens from burst, 2 tokens by 50ms
+	require.Less(t, elapsed, 500*time.Millisecond)
+
+	shortCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, b.Wait(shortCtx), context.DeadlineExceeded) // fails without waiting
+
+	r := b.Reserve()
+	require.True(t, r.OK())
+	require.Greater(t, r.Delay(), time.Duration(0))
+	r.Cancel()
+	time.Sleep(60 * time.Millisecond)
+	require.True(t, b.Allow()) // cancelled token is returned
+}
+
+func TestTokenBucketSetLimit(t *testing.T) {
+	b := NewTokenBucket(0, 1)
+	require.True(t, b.Allow())
+	require.False(t, b.Reserve().OK())
+	require.ErrorIs(t, b.Wait(context.Background()), ErrLimitExceeded)
+	require.Equal(t, 0.0, b.Tokens())
+
+	b.SetLimit(1000, 5)
+	rate, burst := b.Limit()
+	require.Equal(t, 1000.0, rate)
+	require.Equal(t, 5, burst)
+	time.Sleep(20 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		require.True(t, b.Allow())
+	}
+}
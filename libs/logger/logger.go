@@ -2,33 +2,45 @@ package logger
 
 import (
 	"context"
+	"fmt"
 	"github.com/opentracing/opentracing-go"
 	"github.com/uber/jaeger-client-go"
 	"net/http"
+	"os"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
-var globalLogger *zap.Logger
+var globalLogger = zap.NewNop() // до Init логи не пишутся
 var staticFields []zap.Field
 
+// contextKey ключ полей логов в контексте
+type contextKey struct{}
+
+// level уровень логов всех логгеров из New, устанавливается при старте через InitLevel и меняется на лету через SetLevel или LevelHandler
+var level = zap.NewAtomicLevel()
+
+// LevelEnv переменная окружения с уровнем логов, имеет приоритет над уровнем из конфигурации
+const LevelEnv = "LOG_LEVEL"
+
 func Init(devel bool, fields ...zap.Field) {
 	globalLogger = New(devel)
 	staticFields = fields
 }
 
+// New создает логгер с общим уровнем логов (см. InitLevel), создание логгера уровень не меняет
 func New(devel bool) *zap.Logger {
-	var logger *zap.Logger
-	var err error
+	var cfg zap.Config
 	if devel {
-		logger, err = zap.NewDevelopment()
+		cfg = zap.NewDevelopmentConfig()
 	} else {
-		cfg := zap.NewProductionConfig()
+		cfg = zap.NewProductionConfig()
 		cfg.DisableCaller = true
 		cfg.DisableStacktrace = true
-		cfg.Level = zap.NewAtomicLevelAt(zap.DebugLevel) /* TODO: поменять на подходящий для прода уровень логов */
-		logger, err = cfg.Build()
 	}
+	cfg.Level = level
+	logger, err := cfg.Build()
 	if err != nil {
 		panic(err)
 	}
@@ -36,6 +48,54 @@ func New(devel bool) *zap.Logger {
 	return logger
 }
 
+// InitLevel устанавливает начальный уровень логов, вызывается один раз при старте сервиса
+// Уровень берется из переменной окружения LOG_LEVEL, если она не задана, то из конфигурации сервиса, например "warn",
+// если и он пустой, то используется debug в режиме разработки и info в проде
+func InitLevel(devel bool, configLevel string) error {
+	if env := os.Getenv(LevelEnv); env != "" {
+		if err := SetLevel(env); err != nil {
+			return fmt.Errorf("invalid %s: %w", LevelEnv, err)
+		}
+		return nil
+	}
+	if configLevel != "" {
+		return SetLevel(configLevel)
+	}
+	if devel {
+		level.SetLevel(zap.DebugLevel)
+	} else {
+		level.SetLevel(zap.InfoLevel)
+	}
+	return nil
+}
+
+// SetLevel меняет уровень логов на лету
+func SetLevel(text string) error {
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(text)); err != nil {
+		return fmt.Errorf("parsing log level: %w", err)
+	}
+	level.SetLevel(l)
+	return nil
+}
+
+// Level возвращает текущий уровень логов
+func Level() zapcore.Level {
+	return level.Level()
+}
+
+// LevelHandler возвращает HTTP обработчик для просмотра и изменения уровня логов на лету:
+// GET возвращает {"level":"info"}, PUT с телом {"level":"debug"} меняет уровень
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		before := level.Level()
+		level.ServeHTTP(w, r)
+		if after := level.Level(); after != before {
+			globalLogger.Warn("log level changed", zap.Stringer("from", before), zap.Stringer("to", after), zap.String("remote", r.RemoteAddr))
+		}
+	})
+}
+
 func Middleware(logger *zap.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		logger.Debug(
@@ -48,20 +108,58 @@ func Middleware(logger *zap.Logger, next http.Handler) http.Handler {
 	})
 }
 
-func Debug(msg string, fields ...zap.Field) {
-	fields = append(fields, staticFields...)
-	globalLogger.Debug(msg, fields...)
+// Debug, Info, Warn, Error и Fatal пишут в глобальный логгер с полями из ctx (см. FromContext)
+// Если контекста запроса нет, например при старте сервиса, передается context.Background()
+// Логи отключенного уровня отбрасываются до сборки логгера из ctx, поля контекста для них не кодируются
+
+func Debug(ctx context.Context, msg string, fields ...zap.Field) {
+	if globalLogger.Core().Enabled(zap.DebugLevel) {
+		FromContext(ctx).Debug(msg, fields...)
+	}
 }
 
-func Info(msg string, fields ...zap.Field) {
-	fields = append(fields, staticFields...)
-	globalLogger.Info(msg, fields...)
+func Info(ctx context.Context, msg string, fields ...zap.Field) {
+	if globalLogger.Core().Enabled(zap.InfoLevel) {
+		FromContext(ctx).Info(msg, fields...)
+	}
 }
 
 func Error(ctx context.Context, msg string, fields ...zap.Field) {
-	fields = append(fields, staticFields...)
-	span := opentracing.SpanFromContext(ctx)
-	if span != nil {
+	if globalLogger.Core().Enabled(zap.ErrorLevel) {
+		FromContext(ctx).Error(msg, fields...)
+	}
+}
+
+func Warn(ctx context.Context, msg string, fields ...zap.Field) {
+	if globalLogger.Core().Enabled(zap.WarnLevel) {
+		FromContext(ctx).Warn(msg, fields...)
+	}
+}
+
+func Fatal(ctx context.Context, msg string, fields ...zap.Field) {
+	FromContext(ctx).Fatal(msg, fields...)
+}
+
+// With возвращает контекст с полями, которые добавляются ко всем логам через FromContext, например ID пользователя или заказа
+func With(ctx context.Context, fields ...zap.Field) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	parent := contextFields(ctx)
+	merged := make([]zap.Field, 0, len(parent)+len(fields))
+	merged = append(merged, parent...)
+	merged = append(merged, fields...)
+	return context.WithValue(ctx, contextKey{}, merged)
+}
+
+// FromContext возвращает логгер с полями из With, ID трейса и спана Jaeger из ctx и статическими полями сервиса
+// Поля кодируются при каждом вызове, даже если уровень логов отключен, поэтому для отдельных логов лучше Debug, Info, Warn и Error
+func FromContext(ctx context.Context) *zap.Logger {
+	if ctx == nil {
+		return globalLogger.With(staticFields...)
+	}
+	fields := append([]zap.Field(nil), contextFields(ctx)...)
+	if span := opentracing.SpanFromContext(ctx); span != nil {
 		if spancontext, ok := span.Context().(jaeger.SpanContext); ok {
 			fields = append(
 				fields,
@@ -70,15 +168,14 @@ func Error(ctx context.Context, msg string, fields ...zap.Field) {
 			)
 		}
 	}
-	globalLogger.Error(msg, fields...)
-}
-
-func Warn(msg string, fields ...zap.Field) {
 	fields = append(fields, staticFields...)
-	globalLogger.Warn(msg, fields...)
+	return globalLogger.With(fields...)
 }
 
-func Fatal(msg string, fields ...zap.Field) {
-	fields = append(fields, staticFields...)
-	globalLogger.Fatal(msg, fields...)
+func contextFields(ctx context.Context) []zap.Field {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(contextKey{}).([]zap.Field)
+	return fields
 }
@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLevel(t *testing.T) {
+	require.NoError(t, InitLevel(false, ""))
+	require.Equal(t, zap.InfoLevel, Level())
+	require.NoError(t, InitLevel(true, ""))
+	require.Equal(t, zap.DebugLevel, Level())
+
+	require.NoError(t, InitLevel(false, "warn"))
+	require.Equal(t, zap.WarnLevel, Level())
+	require.Error(t, SetLevel("verbose"))
+
+	require.NoError(t, SetLevel("error"))
+	Init(true) // new logger doesn't reset level changed at runtime
+	require.Equal(t, zap.ErrorLevel, Level())
+
+	t.Setenv(LevelEnv, "dpanic")
+	require.NoError(t, InitLevel(true, "debug")) // env has priority over config
+	require.Equal(t, zap.DPanicLevel, Level())
+
+	t.Setenv(LevelEnv, "loud")
+	require.Error(t, InitLevel(true, ""))
+}
+
+func TestLevelHandler(t *testing.T) {
+	Init(false)
+	handler := LevelHandler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(`{"level":"debug"}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, zap.DebugLevel, Level())
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/log/level", nil))
+	require.JSONEq(t, `{"level":"debug"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(`{"level":"loud"}`)))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, zap.DebugLevel, Level())
+}
+
+func TestFromContext(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	globalLogger = zap.New(core)
+	staticFields = []zap.Field{zap.String("service", "test")}
+
+	ctx := With(context.Background(), zap.String("method", "/loms.Loms/CreateOrder"))
+	ctx = With(ctx, zap.Int64("orderID", 42))
+	FromContext(ctx).Info("order created")
+	Error(ctx, "failed")
+	Debug(ctx, "details")
+	FromContext(context.Background()).Info("no fields")
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 4)
+	require.Equal(t, map[string]interface{}{
+		"method":  "/loms.Loms/CreateOrder",
+		"orderID": int64(42),
+		"service": "test",
+	}, entries[0].ContextMap())
+	require.Equal(t, entries[0].ContextMap(), entries[1].ContextMap())
+	require.Equal(t, entries[0].ContextMap(), entries[2].ContextMap())
+	require.Equal(t, map[string]interface{}{"service": "test"}, entries[3].ContextMap())
+}
+
+func TestDisabledLevel(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	globalLogger = zap.New(core)
+	staticFields = []zap.Field{zap.String("service", "test")}
+
+	ctx := With(context.Background(), zap.Int64("orderID", 42))
+	allocs := testing.AllocsPerRun(100, func() {
+		Debug(ctx, "details")
+	})
+	require.Zero(t, allocs)
+
+	Info(ctx, "order created")
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 1)
+	require.Equal(t, map[string]interface{}{"orderID": int64(42), "service": "test"}, entries[0].ContextMap())
+}
+
+func BenchmarkDisabledDebug(b *testing.B) {
+	globalLogger = zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(io.Discard), zap.InfoLevel))
+	ctx := With(context.Background(), zap.Int64("orderID", 42), zap.String("method", "/loms.Loms/CreateOrder"))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Debug(ctx, "details", zap.Int("items", 3))
+	}
+}
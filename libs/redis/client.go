@@ -0,0 +1,314 @@
+// Minimal Redis client
+// Supports only commands needed for cache backend: GET, MGET, SET with expiration, DEL, SCAN and PING, other commands can be sent with Do
+// Connections are kept in pool of idle connections, new connection is opened when pool is empty,
+// connection is closed instead of returning to pool after network or protocol error
+// Context deadline is applied to every command as connection deadline
+
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPoolSize    = 10
+	defaultDialTimeout = 5 * time.Second
+	scanCount          = "100" // Keys per SCAN call for DeletePrefix
+)
+
+var ErrClosed = errors.New("redis client is closed")
+
+type Options struct { // client parameters
+	Addr        string        // Server address host:port
+	Password    string        // Password for AUTH, empty - no authentication
+	DB          int           // Database number for SELECT
+	PoolSize    int           // Maximum number of idle connections, 0 - 10
+	DialTimeout time.Duration // Timeout for opening connection, 0 - 5 seconds
+}
+
+type Client struct {
+	options Options
+	idle    chan *conn    // Pool of idle connections
+	closed  chan struct{} // Closed by Close
+	once    sync.Once
+}
+
+type conn struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+	writer  *bufio.Writer
+}
+
+// New creates client, connections are opened on first command
+func New(options Options) *Client {
+	if options.PoolSize <= 0 {
+		options.PoolSize = defaultPoolSize
+	}
+	if options.DialTimeout <= 0 {
+		options.DialTimeout = defaultDialTimeout
+	}
+	return &Client{
+		options: options,
+		idle:    make(chan *conn, options.PoolSize),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Do sends command and returns reply, error reply from server is returned as Error
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	byteArgs := make([][]byte, len(args))
+	for i, arg := range args {
+		byteArgs[i] = []byte(arg)
+	}
+	return c.do(ctx, byteArgs...)
+}
+
+// Get returns value of key, false if key doesn't exist
+func (c *Client) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := c.do(ctx, []byte("GET"), []byte(key))
+	if err != nil {
+		return nil, false, err
+	}
+	switch value := reply.(type) {
+	case nil:
+		return nil, false, nil
+	case []byte:
+		return value, true, nil
+	default:
+		return nil, false, fmt.Errorf("unexpected GET reply %T", reply)
+	}
+}
+
+// GetMany returns values of existing keys with one MGET command, missing keys are absent in result
+func (c *Client) GetMany(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+	args := make([][]byte, 0, len(keys)+1)
+	args = append(args, []byte("MGET"))
+	for _, key := range keys {
+		args = append(args, []byte(key))
+	}
+	reply, err := c.do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]any)
+	if !ok || len(items) != len(keys) {
+		return nil, fmt.Errorf("unexpected MGET reply %T", reply)
+	}
+	for i, item := range items {
+		switch value := item.(type) {
+		case nil:
+		case []byte:
+			values[keys[i]] = value
+		default:
+			return nil, fmt.Errorf("unexpected MGET value %T", item)
+		}
+	}
+	return values, nil
+}
+
+// Set sets value of key, key expires after ttl if it is positive
+func (c *Client) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := [][]byte{[]byte("SET"), []byte(key), value}
+	if ttl > 0 {
+		args = append(args, []byte("PX"), []byte(strconv.FormatInt(ttl.Milliseconds(), 10)))
+	}
+	_, err := c.do(ctx, args...)
+	return err
+}
+
+// Delete removes keys
+func (c *Client) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := make([][]byte, 0, len(keys)+1)
+	args = append(args, []byte("DEL"))
+	for _, key := range keys {
+		args = append(args, []byte(key))
+	}
+	_, err := c.do(ctx, args...)
+	return err
+}
+
+// DeletePrefix removes all keys starting with prefix, keys are found with SCAN, so server is not blocked
+// Keys added concurrently with DeletePrefix may stay, empty prefix removes all keys of database
+func (c *Client) DeletePrefix(ctx context.Context, prefix string) error {
+	pattern := globEscape(prefix) + "*"
+	cursor := "0"
+	for {
+		reply, err := c.do(ctx, []byte("SCAN"), []byte(cursor), []byte("MATCH"), []byte(pattern), []byte("COUNT"), []byte(scanCount))
+		if err != nil {
+			return err
+		}
+		next, keys, err := parseScanReply(reply)
+		if err != nil {
+			return err
+		}
+		if err := c.Delete(ctx, keys...); err != nil {
+			return err
+		}
+		if next == "0" {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// Ping checks connection to server
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.do(ctx, []byte("PING"))
+	return err
+}
+
+// Close closes idle connections, connections in use are closed when commands are finished
+func (c *Client) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	for {
+		select {
+		case cn := <-c.idle:
+			_ = cn.netConn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *Client) do(ctx context.Context, args ...[]byte) (any, error) {
+	cn, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := cn.do(ctx, args...)
+	if err != nil {
+		_ = cn.netConn.Close()
+		return nil, err
+	}
+	c.release(cn)
+	if replyErr, ok := reply.(Error); ok {
+		return nil, replyErr
+	}
+	return reply, nil
+}
+
+func (c *Client) acquire(ctx context.Context) (*conn, error) { // takes idle connection or opens new one
+	select {
+	case <-c.closed:
+		return nil, ErrClosed
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: c.options.DialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.options.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to redis: %w", err)
+	}
+	cn := &conn{
+		netConn: netConn,
+		reader:  bufio.NewReader(netConn),
+		writer:  bufio.NewWriter(netConn),
+	}
+	if c.options.Password != "" {
+		if err := cn.init(ctx, "AUTH", c.options.Password); err != nil {
+			return nil, err
+		}
+	}
+	if c.options.DB != 0 {
+		if err := cn.init(ctx, "SELECT", strconv.Itoa(c.options.DB)); err != nil {
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+func (c *Client) release(cn *conn) { // returns connection to pool or closes it if pool is full or client is closed
+	select {
+	case <-c.closed:
+		_ = cn.netConn.Close()
+		return
+	default:
+	}
+	select {
+	case c.idle <- cn:
+	default:
+		_ = cn.netConn.Close()
+	}
+}
+
+func (cn *conn) init(ctx context.Context, command, arg string) error { // sends connection setup command, closes connection on failure
+	reply, err := cn.do(ctx, []byte(command), []byte(arg))
+	if err == nil {
+		if replyErr, ok := reply.(Error); ok {
+			err = replyErr
+		}
+	}
+	if err != nil {
+		_ = cn.netConn.Close()
+		return fmt.Errorf("redis %s: %w", command, err)
+	}
+	return nil
+}
+
+func (cn *conn) do(ctx context.Context, args ...[]byte) (any, error) {
+	deadline, _ := ctx.Deadline() // zero deadline means no deadline
+	if err := cn.netConn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	if err := writeCommand(cn.writer, args...); err != nil {
+		return nil, fmt.Errorf("sending redis command: %w", err)
+	}
+	reply, err := ReadReply(cn.reader)
+	if err != nil {
+		return nil, fmt.Errorf("reading redis reply: %w", err)
+	}
+	return reply, nil
+}
+
+func parseScanReply(reply any) (string, []string, error) { // returns next cursor and keys from SCAN reply
+	items, ok := reply.([]any)
+	if !ok || len(items) != 2 {
+		return "", nil, fmt.Errorf("unexpected SCAN reply %T", reply)
+	}
+	cursor, ok := items[0].([]byte)
+	if !ok {
+		return "", nil, fmt.Errorf("unexpected SCAN cursor %T", items[0])
+	}
+	rawKeys, ok := items[1].([]any)
+	if !ok {
+		return "", nil, fmt.Errorf("unexpected SCAN keys %T", items[1])
+	}
+	keys := make([]string, 0, len(rawKeys))
+	for _, rawKey := range rawKeys {
+		key, ok := rawKey.([]byte)
+		if !ok {
+			return "", nil, fmt.Errorf("unexpected SCAN key %T", rawKey)
+		}
+		keys = append(keys, string(key))
+	}
+	return string(cursor), keys, nil
+}
+
+func globEscape(s string) string { // escapes special characters of SCAN MATCH pattern
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
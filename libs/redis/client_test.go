@@ -0,0 +1,112 @@
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"route256/libs/redis"
+	"route256/libs/redis/redistest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	server, err := redistest.NewServer()
+	require.NoError(t, err)
+	defer server.Close()
+
+	client := redis.New(redis.Options{Addr: server.Addr(), PoolSize: 2})
+	defer client.Close()
+
+	require.NoError(t, client.Ping(ctx))
+
+	_, ok, err := client.Get(ctx, "missing")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, client.Set(ctx, "key", []byte("value\r\nwith CRLF"), 0))
+	value, ok, err := client.Get(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "value\r\nwith CRLF", string(value))
+
+	require.NoError(t, client.Set(ctx, "short", []byte("1"), 50*time.Millisecond))
+	time.Sleep(100 * time.Millisecond)
+	_, ok, err = client.Get(ctx, "short")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, client.Delete(ctx, "key", "missing"))
+	_, ok, err = client.Get(ctx, "key")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	reply, err := client.Do(ctx, "DBSIZE")
+	require.NoError(t, err)
+	require.Equal(t, int64(0), reply)
+
+	_, err = client.Do(ctx, "UNKNOWN")
+	var replyErr redis.Error
+	require.ErrorAs(t, err, &replyErr)
+	require.NoError(t, client.Ping(ctx)) // connection is usable after error reply
+}
+
+func TestClientGetMany(t *testing.T) {
+	ctx := context.Background()
+	server, err := redistest.NewServer()
+	require.NoError(t, err)
+	defer server.Close()
+	client := redis.New(redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	require.NoError(t, client.Set(ctx, "a", []byte("1"), 0))
+	require.NoError(t, client.Set(ctx, "c", []byte{}, 0))
+	values, err := client.GetMany(ctx, "a", "b", "c")
+	require.NoError(t, err)
+	require.Equal(t, map[string][]byte{"a": []byte("1"), "c": {}}, values)
+	require.Equal(t, 1, server.Commands("MGET"))
+
+	values, err = client.GetMany(ctx)
+	require.NoError(t, err)
+	require.Empty(t, values)
+	require.Equal(t, 1, server.Commands("MGET"))
+}
+
+func TestClientDeletePrefix(t *testing.T) {
+	ctx := context.Background()
+	server, err := redistest.NewServer()
+	require.NoError(t, err)
+	defer server.Close()
+	client := redis.New(redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	for i := 0; i < 250; i++ {
+		require.NoError(t, client.Set(ctx, fmt.Sprintf("products:%d", i), []byte("1"), 0))
+	}
+	require.NoError(t, client.Set(ctx, "products*", []byte("1"), 0))
+	require.NoError(t, client.Set(ctx, "carts:1", []byte("1"), 0))
+
+	require.NoError(t, client.DeletePrefix(ctx, "products:"))
+	reply, err := client.Do(ctx, "DBSIZE")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), reply) // pattern characters in prefix are escaped
+	require.Greater(t, server.Commands("SCAN"), 1)
+}
+
+func TestClientReconnect(t *testing.T) {
+	ctx := context.Background()
+	server, err := redistest.NewServer()
+	require.NoError(t, err)
+	client := redis.New(redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	require.NoError(t, client.Ping(ctx))
+	require.NoError(t, server.Close())
+	require.Error(t, client.Ping(ctx))
+
+	require.NoError(t, client.Close())
+	require.ErrorIs(t, client.Ping(ctx), redis.ErrClosed)
+}
@@ -0,0 +1,309 @@
+// In-memory Redis server for tests
+// Supports PING, GET, MGET, SET with EX/PX expiration, DEL, EXISTS, SCAN with MATCH/COUNT, DBSIZE and FLUSHALL over RESP,
+// MATCH patterns support only *, ? and backslash escapes
+// listens on random local port, so tests don't need real Redis
+
+package redistest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"route256/libs/redis"
+)
+
+type Server struct {
+	listener net.Listener
+	lock     sync.Mutex
+	data     map[string]item
+	commands map[string]int // Number of received commands by name
+	cursors  []string       // Last keys returned by SCAN, cursor n continues after cursors[n-1]
+	conns    map[net.Conn]struct{}
+	handlers sync.WaitGroup
+}
+
+type item struct {
+	value     []byte
+	expiresAt time.Time // zero - no expiration
+}
+
+// NewServer starts server on random local port, it must be stopped with Close
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		listener: listener,
+		data:     make(map[string]item),
+		commands: make(map[string]int),
+		conns:    make(map[net.Conn]struct{}),
+	}
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns server address for redis.Options
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Commands returns number of received commands with name, for example "GET"
+func (s *Server) Commands(name string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.commands[strings.ToUpper(name)]
+}
+
+// Close stops server, closes client connections and waits for their handlers
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.lock.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.lock.Unlock()
+	s.handlers.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.lock.Lock()
+		s.conns[conn] = struct{}{}
+		s.lock.Unlock()
+		s.handlers.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer s.handlers.Done()
+	defer func() {
+		s.lock.Lock()
+		delete(s.conns, conn)
+		s.lock.Unlock()
+		_ = conn.Close()
+	}()
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	for {
+		args, err := redis.ReadCommand(reader)
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			writeError(writer, "ERR empty command")
+		} else {
+			s.exec(writer, strings.ToUpper(string(args[0])), args[1:])
+		}
+		if err := writer.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) exec(w *bufio.Writer, command string, args [][]byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.commands[command]++
+	switch command {
+	case "PING":
+		writeSimple(w, "PONG")
+	case "GET":
+		if len(args) != 1 {
+			writeError(w, "ERR wrong number of arguments for 'get' command")
+			return
+		}
+		it, ok := s.get(string(args[0]))
+		if !ok {
+			_, _ = w.WriteString("$-1\r\n")
+			return
+		}
+		writeBulk(w, it.value)
+	case "MGET":
+		if len(args) == 0 {
+			writeError(w, "ERR wrong number of arguments for 'mget' command")
+			return
+		}
+		_, _ = fmt.Fprintf(w, "*%d\r\n", len(args))
+		for _, key := range args {
+			if it, ok := s.get(string(key)); ok {
+				writeBulk(w, it.value)
+			} else {
+				_, _ = w.WriteString("$-1\r\n")
+			}
+		}
+	case "SET":
+		if len(args) != 2 && len(args) != 4 {
+			writeError(w, "ERR syntax error")
+			return
+		}
+		it := item{value: append([]byte(nil), args[1]...)}
+		if len(args) == 4 {
+			amount, err := strconv.ParseInt(string(args[3]), 10, 64)
+			if err != nil || amount <= 0 {
+				writeError(w, "ERR invalid expire time in 'set' command")
+				return
+			}
+			switch strings.ToUpper(string(args[2])) {
+			case "EX":
+				it.expiresAt = time.Now().Add(time.Duration(amount) * time.Second)
+			case "PX":
+				it.expiresAt = time.Now().Add(time.Duration(amount) * time.Millisecond)
+			default:
+				writeError(w, "ERR syntax error")
+				return
+			}
+		}
+		s.data[string(args[0])] = it
+		writeSimple(w, "OK")
+	case "DEL", "EXISTS":
+		count := 0
+		for _, key := range args {
+			if _, ok := s.get(string(key)); ok {
+				count++
+				if command == "DEL" {
+					delete(s.data, string(key))
+				}
+			}
+		}
+		writeInt(w, count)
+	case "SCAN":
+		s.scan(w, args)
+	case "DBSIZE":
+		count := 0
+		for key := range s.data {
+			if _, ok := s.get(key); ok {
+				count++
+			}
+		}
+		writeInt(w, count)
+	case "FLUSHALL":
+		s.data = make(map[string]item)
+		writeSimple(w, "OK")
+	default:
+		writeError(w, fmt.Sprintf("ERR unknown command '%s'", command))
+	}
+}
+
+func (s *Server) scan(w *bufio.Writer, args [][]byte) { // keys are scanned in sorted order, so keys existing during whole scan are returned once
+	if len(args) == 0 || len(args)%2 != 1 {
+		writeError(w, "ERR syntax error")
+		return
+	}
+	cursor, err := strconv.Atoi(string(args[0]))
+	if err != nil || cursor < 0 || cursor > len(s.cursors) {
+		writeError(w, "ERR invalid cursor")
+		return
+	}
+	pattern, count := "*", 10
+	for i := 1; i < len(args); i += 2 {
+		switch strings.ToUpper(string(args[i])) {
+		case "MATCH":
+			pattern = string(args[i+1])
+		case "COUNT":
+			if count, err = strconv.Atoi(string(args[i+1])); err != nil || count <= 0 {
+				writeError(w, "ERR syntax error")
+				return
+			}
+		default:
+			writeError(w, "ERR syntax error")
+			return
+		}
+	}
+
+	keys := make([]string, 0, len(s.data))
+	for key := range s.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	i := 0
+	if cursor > 0 {
+		i = sort.Search(len(keys), func(i int) bool { return keys[i] > s.cursors[cursor-1] })
+	}
+	var found []string
+	for ; i < len(keys) && count > 0; i, count = i+1, count-1 {
+		if _, ok := s.get(keys[i]); ok && match(pattern, keys[i]) {
+			found = append(found, keys[i])
+		}
+	}
+	next := 0
+	if i < len(keys) {
+		s.cursors = append(s.cursors, keys[i-1])
+		next = len(s.cursors)
+	}
+	_, _ = w.WriteString("*2\r\n")
+	writeBulk(w, []byte(strconv.Itoa(next)))
+	_, _ = fmt.Fprintf(w, "*%d\r\n", len(found))
+	for _, key := range found {
+		writeBulk(w, []byte(key))
+	}
+}
+
+func match(pattern, key string) bool { // glob matching with *, ? and backslash escapes
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(key); i >= 0; i-- {
+				if match(pattern[1:], key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+		default:
+			if pattern[0] == '\\' && len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			if len(key) == 0 || key[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, key = pattern[1:], key[1:]
+	}
+	return len(key) == 0
+}
+
+func (s *Server) get(key string) (item, bool) { // returns item if it exists and is not expired
+	it, ok := s.data[key]
+	if ok && !it.expiresAt.IsZero() && !time.Now().Before(it.expiresAt) {
+		delete(s.data, key)
+		return item{}, false
+	}
+	return it, ok
+}
+
+func writeSimple(w *bufio.Writer, value string) {
+	_, _ = fmt.Fprintf(w, "+%s\r\n", value)
+}
+
+func writeError(w *bufio.Writer, message string) {
+	_, _ = fmt.Fprintf(w, "-%s\r\n", message)
+}
+
+func writeInt(w *bufio.Writer, value int) {
+	_, _ = fmt.Fprintf(w, ":%d\r\n", value)
+}
+
+func writeBulk(w *bufio.Writer, value []byte) {
+	_, _ = fmt.Fprintf(w, "$%d\r\n", len(value))
+	_, _ = w.Write(value)
+	_, _ = w.WriteString("\r\n")
+}
@@ -0,0 +1,116 @@
+// RESP (REdis Serialization Protocol) encoding
+// Commands are sent as arrays of bulk strings, replies can be simple strings, errors, integers, bulk strings or arrays
+// Nil bulk string and nil array are returned as nil
+
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type Error string // Error reply from server
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+func writeCommand(w *bufio.Writer, args ...[]byte) error { // writes command as array of bulk strings
+	if _, err := fmt.Fprintf(w, "*%d\r\n", len(args)); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		if _, err := fmt.Fprintf(w, "$%d\r\n", len(arg)); err != nil {
+			return err
+		}
+		if _, err := w.Write(arg); err != nil {
+			return err
+		}
+		if _, err := w.WriteString("\r\n"); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// ReadReply reads one reply: string for simple string, Error for error, int64 for integer,
+// []byte for bulk string, []any for array, nil for nil bulk string or array
+func ReadReply(r *bufio.Reader) (any, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, fmt.Errorf("empty RESP line")
+	}
+	switch line[0] {
+	case '+':
+		return string(line[1:]), nil
+	case '-':
+		return Error(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(string(line[1:]), 10, 64)
+	case '$':
+		size, err := strconv.Atoi(string(line[1:]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid bulk string size: %w", err)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	case '*':
+		count, err := strconv.Atoi(string(line[1:]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid array size: %w", err)
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]any, count)
+		for i := range items {
+			if items[i], err = ReadReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unknown RESP type %q", line[0])
+	}
+}
+
+// ReadCommand reads command sent by client as array of bulk strings, used by servers
+func ReadCommand(r *bufio.Reader) ([][]byte, error) {
+	reply, err := ReadReply(r)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]any)
+	if !ok {
+		return nil, fmt.Errorf("command must be array, got %T", reply)
+	}
+	args := make([][]byte, len(items))
+	for i, item := range items {
+		if args[i], ok = item.([]byte); !ok {
+			return nil, fmt.Errorf("command argument must be bulk string, got %T", item)
+		}
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) ([]byte, error) { // reads line without CRLF
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("invalid RESP line ending")
+	}
+	return line[:len(line)-2], nil
+}
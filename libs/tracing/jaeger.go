@@ -1,6 +1,8 @@
 package tracing
 
 import (
+	"context"
+
 	"github.com/uber/jaeger-client-go/config"
 	"go.uber.org/zap"
 	"route256/libs/logger"
@@ -18,11 +20,11 @@ func Init(serviceName string) {
 
 	cfg, err := cfg.FromEnv()
 	if err != nil {
-		logger.Fatal("Cannot init tracing", zap.Error(err))
+		logger.Fatal(context.Background(), "Cannot init tracing", zap.Error(err))
 	}
 
 	_, err = cfg.InitGlobalTracer(serviceName)
 	if err != nil {
-		logger.Fatal("Cannot init tracing", zap.Error(err))
+		logger.Fatal(context.Background(), "Cannot init tracing", zap.Error(err))
 	}
 }
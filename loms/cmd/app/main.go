@@ -6,7 +6,9 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"route256/libs/interceptors"
+	"route256/libs/jobs"
 	log "route256/libs/logger"
 	"route256/libs/metrics"
 	"route256/libs/tracing"
@@ -18,6 +20,8 @@ import (
 	"route256/loms/internal/service"
 	desc "route256/loms/pkg/loms_v1"
 	"sync"
+	"syscall"
+	"time"
 
 	grpcMiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/grpc-ecosystem/grpc-opentracing/go/otgrpc"
@@ -34,6 +38,9 @@ var (
 	develMode   = flag.Bool("devel", false, "development mode")
 )
 
+// jobsShutdownTimeout время ожидания выполняющихся фоновых задач при остановке сервиса
+const jobsShutdownTimeout = 30 * time.Second
+
 var brokers = []string{
 	"kafka1:29091",
 	"kafka2:29092",
@@ -47,7 +54,10 @@ func main() {
 
 	err := config.Init()
 	if err != nil {
-		log.Fatal("config init", zap.Error(err))
+		log.Fatal(context.Background(), "config init", zap.Error(err))
+	}
+	if err := log.InitLevel(*develMode, config.ConfigData.LogLevel); err != nil {
+		log.Fatal(context.Background(), "log level init", zap.Error(err))
 	}
 
 	tracing.Init("loms")
@@ -56,11 +66,11 @@ func main() {
 	defer cancel()
 	pool, err := pgxpool.Connect(ctx, os.Getenv("DATABASE_URL"))
 	if err != nil {
-		log.Fatal("failed to connect db", zap.Error(err))
+		log.Fatal(ctx, "failed to connect db", zap.Error(err))
 	}
 	defer pool.Close()
 	if err := pool.Ping(ctx); err != nil {
-		log.Fatal("failed to ping db", zap.Error(err))
+		log.Fatal(ctx, "failed to ping db", zap.Error(err))
 	}
 
 	txman := tranman.NewTransactionManager(pool)
@@ -68,12 +78,17 @@ func main() {
 
 	sender, err := kafka.NewSender(brokers, "orders")
 	if err != nil {
-		log.Fatal("error connecting to kafka", zap.Error(err))
+		log.Fatal(ctx, "error connecting to kafka", zap.Error(err))
 	}
-	lomsService := service.New(lomsRepo, txman, sender)
-	err = lomsService.StartJobs(ctx)
-	if err != nil {
-		log.Fatal("error starting jobs", zap.Error(err))
+	leader := jobs.NewPostgresLeader(ctx, pool, "loms-jobs", 0)
+	defer leader.Close()
+	lomsService := service.New(lomsRepo, txman, sender, config.ConfigData.OutboxConcurrency, leader)
+	jobsManager := jobs.NewManager()
+	if err := jobsManager.Add(lomsService.Jobs()...); err != nil {
+		log.Fatal(ctx, "error registering jobs", zap.Error(err))
+	}
+	if err := jobsManager.Start(ctx); err != nil {
+		log.Fatal(ctx, "error starting jobs", zap.Error(err))
 	}
 
 	metricsServerDone := &sync.WaitGroup{}
@@ -85,8 +100,13 @@ func main() {
 	go func(ctx context.Context) {
 		defer metricsServerDone.Done()
 		http.Handle("/metrics", metrics.New())
+		// PUT и POST принимаются только с токеном администратора или, если он не задан, только с localhost
+		http.Handle("/log/level", interceptors.NewAdminHTTPHandler(config.ConfigData.AdminToken, log.LevelHandler())) // GET - текущий уровень логов, PUT {"level":"debug"} - изменить
+		jobsAdmin := interceptors.NewAdminHTTPHandler(config.ConfigData.AdminToken, jobs.NewAdminHandler(jobsManager, "/jobs"))
+		http.Handle("/jobs", jobsAdmin) // статусы фоновых задач и запуск вне расписания
+		http.Handle("/jobs/", jobsAdmin)
 
-		log.Info("listening http for metrics", zap.String("addr", *metricsPort))
+		log.Info(ctx, "listening http for metrics", zap.String("addr", *metricsPort))
 		if err := metricsServer.ListenAndServe(); err != nil {
 			log.Error(ctx, "Error starting metrics handler", zap.Error(err))
 		}
@@ -96,13 +116,14 @@ func main() {
 
 	lis, err := net.Listen("tcp", *grpcPort)
 	if err != nil {
-		log.Fatal("failed to listen", zap.Error(err))
+		log.Fatal(ctx, "failed to listen", zap.Error(err))
 	}
 
 	s := grpc.NewServer(
 		grpc.UnaryInterceptor(
 			grpcMiddleware.ChainUnaryServer(
 				otgrpc.OpenTracingServerInterceptor(opentracing.GlobalTracer()),
+				interceptors.NewRateLimitInterceptor(ctx, config.ConfigData.RateLimit),
 				interceptors.LoggingInterceptor,
 			),
 		),
@@ -111,10 +132,24 @@ func main() {
 	reflection.Register(s)
 	desc.RegisterLOMSServiceServer(s, loms_v1.NewLOMSV1(lomsService))
 
-	log.Info("server listening", zap.String("grpcPort", *grpcPort))
+	log.Info(ctx, "server listening", zap.String("grpcPort", *grpcPort))
+
+	go func() { // при остановке сервиса завершаем обработку запросов и дожидаемся выполняющихся фоновых задач
+		sigterm := make(chan os.Signal, 1)
+		signal.Notify(sigterm, syscall.SIGINT, syscall.SIGTERM)
+		<-sigterm
+		log.Info(ctx, "terminating: via signal")
+		s.GracefulStop()
+	}()
 
 	if err = s.Serve(lis); err != nil {
-		log.Fatal("failed to serve", zap.Error(err))
+		log.Fatal(ctx, "failed to serve", zap.Error(err))
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), jobsShutdownTimeout)
+	defer shutdownCancel()
+	if err := jobsManager.Shutdown(shutdownCtx); err != nil {
+		log.Error(ctx, "Error stopping jobs", zap.Error(err))
 	}
 
 	if err := metricsServer.Shutdown(ctx); err != nil {
@@ -2,12 +2,17 @@ package config
 
 import (
 	"os"
+	"route256/libs/interceptors"
 
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
 )
 
 type ConfigStruct struct {
+	LogLevel          string                       `yaml:"logLevel"`
+	RateLimit         interceptors.RateLimitConfig `yaml:"rateLimit"`
+	OutboxConcurrency int64                        `yaml:"outboxConcurrency"`
+	AdminToken        string                       `yaml:"adminToken"` // Токен административного HTTP API, если пустой, то API изменяет состояние только с localhost
 }
 
 var ConfigData ConfigStruct
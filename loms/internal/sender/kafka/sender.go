@@ -72,6 +72,6 @@ func (s sender) SendNotification(ctx context.Context, msg service.OutboxMessage)
 		return err
 	}
 
-	log.Debug("notification sent", zap.String("orderID", msg.Key), zap.String("message", msg.Message), zap.Int32("partition", partition), zap.Int64("offset", offset))
+	log.Debug(ctx, "notification sent", zap.String("message", msg.Message), zap.Int32("partition", partition), zap.Int64("offset", offset))
 	return nil
 }
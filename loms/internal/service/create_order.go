@@ -3,6 +3,9 @@ package service
 import (
 	"context"
 	"fmt"
+	log "route256/libs/logger"
+
+	"go.uber.org/zap"
 )
 
 func (m *Service) CreateOrder(ctx context.Context, userID int64, items []Item) (int64, error) {
@@ -69,5 +72,7 @@ func (m *Service) CreateOrder(ctx context.Context, userID int64, items []Item) (
 	if err != nil {
 		return -1, err
 	}
+	ctx = log.With(ctx, zap.Int64("orderID", orderID))
+	log.Info(ctx, "order created", zap.Int("items", len(items)))
 	return orderID, nil
 }
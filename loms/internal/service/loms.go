@@ -2,8 +2,8 @@ package service
 
 import (
 	"context"
-	"fmt"
 	"route256/libs/jobs"
+	"route256/libs/limiter"
 	"time"
 
 	"github.com/pkg/errors"
@@ -80,16 +80,24 @@ type Service struct {
 	LOMSRepo                  LOMSRepository
 	TXMan                     TransactionManager
 	NotificationsSender       NotificationsSender
+	OutboxLimiter             *limiter.Semaphore
 	UnpayedOrdersJob          *jobs.Job
 	StaleReservationsJob      *jobs.Job
 	SendOrderNotificationsJob *jobs.Job
 }
 
-func New(lomsRepo LOMSRepository, txman TransactionManager, sender NotificationsSender) *Service {
+// New создает сервис LOMS
+// В outboxConcurrency передается максимальное количество одновременно отправляемых уведомлений, если 0, то 1
+// Фоновые задачи выполняются только на реплике, которую выбрал leader, если nil, то на каждой реплике
+func New(lomsRepo LOMSRepository, txman TransactionManager, sender NotificationsSender, outboxConcurrency int64, leader jobs.Leader) *Service {
+	if outboxConcurrency < 1 {
+		outboxConcurrency = 1
+	}
 	result := &Service{
 		LOMSRepo:            lomsRepo,
 		TXMan:               txman,
 		NotificationsSender: sender,
+		OutboxLimiter:       limiter.NewSemaphore("loms_outbox", outboxConcurrency),
 	}
 	result.UnpayedOrdersJob = jobs.NewJob("Unpayed orders", func(ctx context.Context) error {
 		return result.UnpayedOrders(ctx)
@@ -100,22 +108,28 @@ func New(lomsRepo LOMSRepository, txman TransactionManager, sender Notifications
 	result.SendOrderNotificationsJob = jobs.NewJob("Send order notifications job", func(ctx context.Context) error {
 		return result.SendOrderNotifications(ctx)
 	}, 10*time.Second)
-	return result
-}
-
-func (m *Service) StartJobs(ctx context.Context) error {
-	var result error
-	err := m.UnpayedOrdersJob.Run(ctx)
-	if err != nil {
-		result = fmt.Errorf("error starting job %v", m.UnpayedOrdersJob.Name)
+	result.SendOrderNotificationsJob.RunOnStart = true // уведомления, накопленные пока сервис был остановлен, отправляются сразу
+	// при недоступности kafka повторяем отправку, не дожидаясь следующего запуска через 10s
+	result.SendOrderNotificationsJob.Retry = jobs.RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     3 * time.Second,
+		Jitter:         0.2,
 	}
-	err = m.StaleReservationsJob.Run(ctx)
-	if err != nil {
-		result = errors.WithMessage(result, fmt.Sprintf("error starting job %v", m.StaleReservationsJob.Name))
-	}
-	err = m.SendOrderNotificationsJob.Run(ctx)
-	if err != nil {
-		result = errors.WithMessage(result, fmt.Sprintf("error starting job %v", m.SendOrderNotificationsJob.Name))
+	result.SendOrderNotificationsJob.Timeout = 30 * time.Second
+	result.UnpayedOrdersJob.Timeout = 20 * time.Second
+	result.StaleReservationsJob.Timeout = 40 * time.Second
+	result.UnpayedOrdersJob.Jitter = 5 * time.Second
+	result.StaleReservationsJob.Jitter = 10 * time.Second
+	if leader != nil { // иначе реплики одновременно читают outbox и дублируют уведомления
+		for _, job := range result.Jobs() {
+			job.Leader = leader
+		}
 	}
 	return result
 }
+
+// Jobs возвращает фоновые задачи сервиса для регистрации в jobs.Manager
+func (m *Service) Jobs() []*jobs.Job {
+	return []*jobs.Job{m.UnpayedOrdersJob, m.StaleReservationsJob, m.SendOrderNotificationsJob}
+}
@@ -2,34 +2,71 @@ package service
 
 import (
 	"context"
+	"route256/libs/jobs"
+	log "route256/libs/logger"
+	"sync"
 
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 )
 
+// SendOrderNotifications отправляет уведомления из outbox и удаляет отправленные
+// Уведомления с разными ключами (заказами) отправляются параллельно, но не больше OutboxLimiter одновременно,
+// уведомления с одним ключом отправляются последовательно, чтобы сохранить порядок смены статусов заказа
+// Если отправленное уведомление не удалось удалить из outbox, ошибка помечается через jobs.Permanent:
+// повтор задачи по RetryPolicy отправил бы его еще раз, поэтому оно удаляется при следующем запуске по расписанию
 func (m *Service) SendOrderNotifications(ctx context.Context) error {
 	outbox, err := m.LOMSRepo.GetOutbox(ctx)
 	if err != nil {
 		return err
 	}
-	var result error
+	byKey := make(map[string][]OutboxMessage)
+	keys := make([]string, 0)
 	for _, msg := range outbox {
-		err = m.NotificationsSender.SendNotification(ctx, msg)
-		if err != nil {
-			if result != nil {
-				result = errors.WithMessage(result, err.Error())
-			} else {
-				result = err
-			}
+		if _, ok := byKey[msg.Key]; !ok {
+			keys = append(keys, msg.Key)
+		}
+		byKey[msg.Key] = append(byKey[msg.Key], msg)
+	}
+
+	var result error
+	var permanent bool // есть отправленные, но не удаленные уведомления
+	var resultLock sync.Mutex
+	addError := func(err error) {
+		resultLock.Lock()
+		defer resultLock.Unlock()
+		permanent = permanent || jobs.IsPermanent(err)
+		if result != nil {
+			result = errors.WithMessage(result, err.Error())
 		} else {
-			err = m.LOMSRepo.DeleteOutbox(ctx, msg.MsgID)
-			if err != nil {
-				if result != nil {
-					result = errors.WithMessage(result, err.Error())
-				} else {
-					result = err
+			result = err
+		}
+	}
+	var wg sync.WaitGroup
+	for _, key := range keys {
+		if err := m.OutboxLimiter.Acquire(ctx, 1); err != nil {
+			addError(err)
+			break
+		}
+		wg.Add(1)
+		go func(ctx context.Context, messages []OutboxMessage) {
+			defer wg.Done()
+			defer m.OutboxLimiter.Release(1)
+			for _, msg := range messages {
+				if err := m.NotificationsSender.SendNotification(ctx, msg); err != nil {
+					addError(err)
+					return // следующие уведомления заказа отправятся после этого при следующем запуске
+				}
+				if err := m.LOMSRepo.DeleteOutbox(ctx, msg.MsgID); err != nil {
+					addError(jobs.Permanent(errors.WithMessage(err, "deleting sent notification from outbox")))
+					return // иначе следующие уведомления заказа будут отправлены раньше повторной отправки этого
 				}
 			}
-		}
+		}(log.With(ctx, zap.String("orderID", key)), byKey[key])
+	}
+	wg.Wait()
+	if permanent {
+		return jobs.Permanent(result)
 	}
 	return result
 }
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"route256/libs/interceptors"
 	log "route256/libs/logger"
 	"route256/libs/metrics"
 	"route256/notifications/internal/kafka"
@@ -31,6 +32,9 @@ func main() {
 	flag.Parse()
 
 	log.Init(*develMode, zap.String("service", "notifications"))
+	if err := log.InitLevel(*develMode, ""); err != nil {
+		log.Fatal(context.Background(), "log level init", zap.Error(err))
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -43,15 +47,17 @@ func main() {
 	go func(ctx context.Context) {
 		defer metricsServerDone.Done()
 		http.Handle("/metrics", metrics.New())
+		// PUT принимается только с localhost, у сервиса нет конфигурации с токеном администратора
+		http.Handle("/log/level", interceptors.NewAdminHTTPHandler("", log.LevelHandler())) // GET - текущий уровень логов, PUT {"level":"debug"} - изменить
 
-		log.Info("listening http for metrics", zap.String("addr", *metricsPort))
+		log.Info(ctx, "listening http for metrics", zap.String("addr", *metricsPort))
 		if err := metricsServer.ListenAndServe(); err != nil {
 			log.Error(ctx, "Error starting metrics handler", zap.Error(err))
 		}
 	}(ctx)
 
 	keepRunning := true
-	log.Info("Starting notifications kafka consumer group...")
+	log.Info(ctx, "Starting notifications kafka consumer group...")
 
 	config := sarama.NewConfig()
 	config.Version = sarama.MaxVersion
@@ -64,7 +70,7 @@ func main() {
 
 	client, err := sarama.NewConsumerGroup(brokers, groupName, config)
 	if err != nil {
-		log.Fatal("Error creating consumer group client", zap.Error(err))
+		log.Fatal(ctx, "Error creating consumer group client", zap.Error(err))
 	}
 
 	consumptionIsPaused := false
@@ -74,7 +80,7 @@ func main() {
 		defer wg.Done()
 		for {
 			if err := client.Consume(ctx, []string{"orders"}, &consumer); err != nil {
-				log.Fatal("Error from consumer", zap.Error(err))
+				log.Fatal(ctx, "Error from consumer", zap.Error(err))
 			}
 			if ctx.Err() != nil {
 				return
@@ -83,7 +89,7 @@ func main() {
 	}()
 
 	<-consumer.Ready()
-	log.Info("Notifications kafka consumer group ready...")
+	log.Info(ctx, "Notifications kafka consumer group ready...")
 
 	sigusr1 := make(chan os.Signal, 1)
 	signal.Notify(sigusr1, syscall.SIGUSR1)
@@ -94,13 +100,13 @@ func main() {
 	for keepRunning {
 		select {
 		case <-ctx.Done():
-			log.Debug("terminating: context cancelled")
+			log.Debug(ctx, "terminating: context cancelled")
 			keepRunning = false
 		case <-sigterm:
-			log.Debug("terminating: via signal")
+			log.Debug(ctx, "terminating: via signal")
 			keepRunning = false
 		case <-sigusr1:
-			toggleConsumptionFlow(client, &consumptionIsPaused)
+			toggleConsumptionFlow(ctx, client, &consumptionIsPaused)
 		}
 	}
 
@@ -112,18 +118,18 @@ func main() {
 	cancel()
 	wg.Wait()
 	if err = client.Close(); err != nil {
-		log.Fatal("Error closing client", zap.Error(err))
+		log.Fatal(ctx, "Error closing client", zap.Error(err))
 	}
 
 }
 
-func toggleConsumptionFlow(client sarama.ConsumerGroup, isPaused *bool) {
+func toggleConsumptionFlow(ctx context.Context, client sarama.ConsumerGroup, isPaused *bool) {
 	if *isPaused {
 		client.ResumeAll()
-		log.Debug("Resuming consumption")
+		log.Debug(ctx, "Resuming consumption")
 	} else {
 		client.PauseAll()
-		log.Debug("Pausing consumption")
+		log.Debug(ctx, "Pausing consumption")
 	}
 
 	*isPaused = !*isPaused
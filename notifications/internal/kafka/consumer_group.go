@@ -1,9 +1,10 @@
 package kafka
 
 import (
-	"log"
+	log "route256/libs/logger"
 
 	"github.com/Shopify/sarama"
+	"go.uber.org/zap"
 )
 
 type Consumer struct {
@@ -33,7 +34,8 @@ func (consumer *Consumer) ConsumeClaim(session sarama.ConsumerGroupSession, clai
 	for {
 		select {
 		case message := <-claim.Messages():
-			log.Printf("New message from orders topic, orderID=%v, state=%v", string(message.Key), string(message.Value))
+			ctx := log.With(session.Context(), zap.String("orderID", string(message.Key)))
+			log.Info(ctx, "New message from orders topic", zap.String("state", string(message.Value)), zap.Int32("partition", message.Partition), zap.Int64("offset", message.Offset))
 			session.MarkMessage(message, "")
 		case <-session.Context().Done():
 			return nil